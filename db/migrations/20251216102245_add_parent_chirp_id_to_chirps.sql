@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+  ADD parent_chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+  ADD deleted_at TIMESTAMP;
+CREATE INDEX chirps_parent_chirp_id_idx ON chirps (parent_chirp_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX chirps_parent_chirp_id_idx;
+ALTER TABLE chirps
+  DROP deleted_at,
+  DROP parent_chirp_id;
+-- +goose StatementEnd
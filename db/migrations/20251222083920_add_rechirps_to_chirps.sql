@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+  ADD repost_of_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+  ADD quote_of_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX chirps_user_id_repost_of_id_idx ON chirps (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL;
+CREATE INDEX chirps_quote_of_id_idx ON chirps (quote_of_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX chirps_quote_of_id_idx;
+DROP INDEX chirps_user_id_repost_of_id_idx;
+ALTER TABLE chirps
+  DROP quote_of_id,
+  DROP repost_of_id;
+-- +goose StatementEnd
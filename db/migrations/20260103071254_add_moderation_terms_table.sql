@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE moderation_terms (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  list TEXT NOT NULL CHECK (list IN ('word', 'pattern', 'domain')),
+  term TEXT NOT NULL,
+  UNIQUE (list, term)
+);
+INSERT INTO moderation_terms (id, created_at, list, term)
+VALUES
+  (gen_random_uuid(), NOW(), 'word', 'kerfuffle'),
+  (gen_random_uuid(), NOW(), 'word', 'sharbert'),
+  (gen_random_uuid(), NOW(), 'word', 'fornax');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE moderation_terms;
+-- +goose StatementEnd
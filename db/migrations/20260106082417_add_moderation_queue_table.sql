@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+  ADD hidden_at TIMESTAMP;
+
+CREATE TABLE moderation_queue (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+  chirp_body TEXT NOT NULL,
+  source TEXT NOT NULL CHECK (source IN ('filter', 'report')),
+  reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  reason TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'hidden', 'deleted')),
+  decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  decided_at TIMESTAMP,
+  decision_reason TEXT
+);
+CREATE INDEX moderation_queue_status_created_at_idx ON moderation_queue (status, created_at, id);
+CREATE INDEX moderation_queue_chirp_id_idx ON moderation_queue (chirp_id);
+CREATE UNIQUE INDEX moderation_queue_pending_report_idx ON moderation_queue (chirp_id, reporter_id)
+  WHERE status = 'pending' AND reporter_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE moderation_queue;
+ALTER TABLE chirps
+  DROP hidden_at;
+-- +goose StatementEnd
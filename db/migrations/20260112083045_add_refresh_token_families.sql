@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+  ADD family_id UUID,
+  ADD replaced_by TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL;
+-- Each existing token starts its own family
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+ALTER TABLE refresh_tokens
+  ALTER family_id SET NOT NULL;
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX refresh_tokens_family_id_idx;
+ALTER TABLE refresh_tokens
+  DROP replaced_by,
+  DROP family_id;
+-- +goose StatementEnd
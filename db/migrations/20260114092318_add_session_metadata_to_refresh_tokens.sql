@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+  ADD user_agent TEXT NOT NULL DEFAULT '',
+  ADD ip_address TEXT NOT NULL DEFAULT '',
+  ADD last_used_at TIMESTAMP;
+UPDATE refresh_tokens SET last_used_at = updated_at;
+ALTER TABLE refresh_tokens
+  ALTER last_used_at SET NOT NULL;
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens
+  DROP last_used_at,
+  DROP ip_address,
+  DROP user_agent;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+  ADD id UUID,
+  ADD token_hash TEXT,
+  ADD replaced_by_id UUID;
+
+-- Hash the existing tokens in place, so clients holding them stay logged in
+UPDATE refresh_tokens
+SET id = gen_random_uuid(),
+    token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+UPDATE refresh_tokens rotated
+SET replaced_by_id = replacement.id
+FROM refresh_tokens replacement
+WHERE replacement.token = rotated.replaced_by;
+
+ALTER TABLE refresh_tokens
+  DROP replaced_by,
+  DROP token;
+ALTER TABLE refresh_tokens
+  RENAME replaced_by_id TO replaced_by;
+ALTER TABLE refresh_tokens
+  ALTER id SET NOT NULL,
+  ALTER token_hash SET NOT NULL,
+  ADD PRIMARY KEY (id),
+  ADD UNIQUE (token_hash),
+  ADD FOREIGN KEY (replaced_by) REFERENCES refresh_tokens(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The raw tokens can't be recovered from their hashes, so everyone is logged out
+DELETE FROM refresh_tokens;
+ALTER TABLE refresh_tokens
+  DROP replaced_by,
+  DROP token_hash,
+  DROP id;
+ALTER TABLE refresh_tokens
+  ADD token TEXT PRIMARY KEY,
+  ADD replaced_by TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL;
+-- +goose StatementEnd
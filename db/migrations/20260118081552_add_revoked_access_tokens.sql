@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The access token issued alongside each refresh token, so revoking a session can revoke it too
+ALTER TABLE refresh_tokens
+  ADD access_token_id TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE revoked_access_tokens (
+  id TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE revoked_access_tokens;
+ALTER TABLE refresh_tokens
+  DROP access_token_id;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Consecutive failed logins, per account (by email address) and per IP address
+CREATE TABLE login_attempts (
+  kind TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  failures INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  last_failed_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (kind, subject)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE login_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webauthn_credentials (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- The authenticator's credential ID, which it sends back on every login
+  credential_id BYTEA NOT NULL UNIQUE,
+  -- COSE-encoded public key
+  public_key BYTEA NOT NULL,
+  attestation_type TEXT NOT NULL,
+  aaguid BYTEA NOT NULL,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  -- The last signature counter the authenticator reported. A counter that
+  -- doesn't increase means the credential may have been cloned.
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  clone_warning BOOLEAN NOT NULL DEFAULT FALSE,
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_at TIMESTAMP
+);
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Challenges issued by a begin request, consumed by the matching finish request
+CREATE TABLE webauthn_sessions (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  -- NULL for login, where the user isn't known until the assertion arrives
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  data JSONB NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webauthn_sessions;
+DROP TABLE webauthn_credentials;
+-- +goose StatementEnd
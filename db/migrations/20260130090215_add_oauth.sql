@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE oauth_clients (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  -- The user who registered the client
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- NULL for public clients, such as mobile apps, which can't keep a secret and rely on PKCE alone
+  secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL
+);
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+CREATE TABLE oauth_grants (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  -- Every scope the user has consented to the client having
+  scopes TEXT[] NOT NULL,
+  PRIMARY KEY (user_id, client_id)
+);
+
+CREATE TABLE oauth_authorization_codes (
+  code_hash TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  -- The S256 PKCE challenge, which the client proves it made when exchanging the code
+  code_challenge TEXT NOT NULL,
+  used_at TIMESTAMP
+);
+
+-- Access tokens issued to clients, so they can be revoked with the grant
+CREATE TABLE oauth_access_tokens (
+  id TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX oauth_access_tokens_user_id_client_id_idx ON oauth_access_tokens (user_id, client_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE oauth_access_tokens;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_grants;
+DROP TABLE oauth_clients;
+-- +goose StatementEnd
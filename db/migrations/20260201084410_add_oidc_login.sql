@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts at external OpenID Connect providers that users log in with
+CREATE TABLE user_identities (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  issuer TEXT NOT NULL,
+  -- The provider's ID for the user, which unlike their email never changes
+  subject TEXT NOT NULL,
+  -- The email the provider had verified when the identity was linked
+  email TEXT NOT NULL,
+  UNIQUE (issuer, subject)
+);
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- Logins sent to the provider, waiting for it to send the user back with a code
+CREATE TABLE oidc_logins (
+  state TEXT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  nonce TEXT NOT NULL,
+  code_verifier TEXT NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE oidc_logins;
+DROP TABLE user_identities;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Logins in progress can't be bound to the client that began them, so they have to be started again
+DELETE FROM oidc_logins;
+-- The hash of the value in the cookie set on the client that began the login, which must be sent back to finish it
+ALTER TABLE oidc_logins
+  ADD binding_hash TEXT NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE oidc_logins
+  DROP binding_hash;
+-- +goose StatementEnd
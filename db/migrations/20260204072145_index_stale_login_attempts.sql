@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Login attempts are deleted once they have been forgotten, found by when they last failed
+CREATE INDEX login_attempts_last_failed_at_idx ON login_attempts (last_failed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX login_attempts_last_failed_at_idx;
+-- +goose StatementEnd
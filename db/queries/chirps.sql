@@ -1,32 +1,168 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 
 -- name: ResetChirps :exec
 DELETE FROM chirps;
 
--- name: GetChirps :many
+-- name: GetChirpByID :one
 SELECT * FROM chirps
+WHERE id = $1
 ORDER BY created_at ASC;
 
--- name: GetChirpByID :one
+-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = $1;
+
+-- name: GetChirpsPageAsc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = sqlc.narg('viewer_id')::uuid)
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+  sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetChirpsPageDesc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = sqlc.narg('viewer_id')::uuid)
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+  sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirps :many
+SELECT chirps.*, ts_rank(chirps.search_vector, to_tsquery('english', sqlc.arg('query'))) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', sqlc.arg('query'))
+AND chirps.deleted_at IS NULL
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+ORDER BY rank DESC, chirps.created_at DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetChirpByIDForUpdate :one
 SELECT * FROM chirps
 WHERE id = $1
-ORDER BY created_at ASC;
+FOR UPDATE;
+
+-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps
+  WHERE parent_chirp_id = $1
+);
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+  SELECT chirps.id, chirps.parent_chirp_id
+  FROM chirps
+  WHERE chirps.id = $1
+  UNION ALL
+  SELECT parent.id, parent.parent_chirp_id
+  FROM chirps parent
+  JOIN ancestors ON parent.id = ancestors.parent_chirp_id
+),
+thread AS (
+  SELECT root.id, root.created_at, root.updated_at, root.body, root.user_id, root.parent_chirp_id, root.deleted_at, root.hidden_at, 0 AS depth
+  FROM chirps root
+  WHERE root.id = (SELECT ancestors.id FROM ancestors WHERE ancestors.parent_chirp_id IS NULL)
+  UNION ALL
+  SELECT reply.id, reply.created_at, reply.updated_at, reply.body, reply.user_id, reply.parent_chirp_id, reply.deleted_at, reply.hidden_at, thread.depth + 1
+  FROM chirps reply
+  JOIN thread ON reply.parent_chirp_id = thread.id
+)
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at, depth
+FROM thread
+ORDER BY depth ASC, created_at ASC;
 
--- name: GetChirpsByUserID :many
+-- name: GetTimelinePage :many
 SELECT *
 FROM chirps
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = sqlc.arg('user_id'))
+AND (
+  user_id = sqlc.arg('user_id')
+  OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id'))
+)
+AND (
+  sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ChirpHasQuotes :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps
+  WHERE quote_of_id = $1
+);
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, repost_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC;
+AND repost_of_id = $2;
 
--- name: DeleteChirp :exec
+-- name: DeleteRechirpsOf :exec
 DELETE FROM chirps
+WHERE repost_of_id = $1;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL,
+    updated_at = NOW()
 WHERE id = $1;
@@ -0,0 +1,50 @@
+-- name: GetLoginAttempts :one
+SELECT * FROM login_attempts
+WHERE kind = $1
+AND subject = $2;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (kind, subject, failures, created_at, last_failed_at)
+VALUES (
+    $1,
+    $2,
+    1,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET failures = CASE WHEN login_attempts.last_failed_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+    last_failed_at = NOW()
+RETURNING *;
+
+-- name: ClearLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE kind = $1
+AND subject = $2;
+
+-- name: CreateLoginAttempts :exec
+INSERT INTO login_attempts (kind, subject, failures, created_at, last_failed_at)
+VALUES (
+    $1,
+    $2,
+    0,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (kind, subject) DO NOTHING;
+
+-- name: GetLoginAttemptsForUpdate :one
+SELECT * FROM login_attempts
+WHERE kind = $1
+AND subject = $2
+FOR UPDATE;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_attempts
+SET failures = GREATEST(failures - 1, 0)
+WHERE kind = $1
+AND subject = $2;
+
+-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failed_at < $1;
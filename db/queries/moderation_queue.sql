@@ -0,0 +1,43 @@
+-- name: CreateModerationItem :exec
+INSERT INTO moderation_queue (id, created_at, updated_at, chirp_id, chirp_body, source, reporter_id, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (chirp_id, reporter_id) WHERE status = 'pending' AND reporter_id IS NOT NULL DO NOTHING;
+
+-- name: GetModerationItem :one
+SELECT * FROM moderation_queue
+WHERE id = $1;
+
+-- name: GetModerationItemForUpdate :one
+SELECT * FROM moderation_queue
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetModerationItemsPage :many
+SELECT *
+FROM moderation_queue
+WHERE status = sqlc.arg('status')
+AND (
+  sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: DecideModerationItems :exec
+UPDATE moderation_queue
+SET status = sqlc.arg('status'),
+    decided_by = sqlc.arg('decided_by'),
+    decided_at = NOW(),
+    decision_reason = sqlc.arg('decision_reason'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+OR (chirp_id = sqlc.narg('chirp_id') AND status = 'pending');
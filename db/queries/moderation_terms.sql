@@ -0,0 +1,4 @@
+-- name: GetModerationTerms :many
+SELECT term FROM moderation_terms
+WHERE list = $1
+ORDER BY term ASC;
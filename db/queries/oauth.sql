@@ -0,0 +1,112 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, owner_id, name, secret_hash, redirect_uris)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetUserOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at;
+
+-- name: UpsertOAuthGrant :one
+INSERT INTO oauth_grants (user_id, client_id, created_at, updated_at, scopes)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NOW(),
+    $3
+)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET updated_at = NOW(),
+    scopes = ARRAY(SELECT DISTINCT unnest(oauth_grants.scopes || EXCLUDED.scopes) ORDER BY 1)
+RETURNING *;
+
+-- name: GetOAuthGrant :one
+SELECT * FROM oauth_grants
+WHERE user_id = $1
+AND client_id = $2;
+
+-- name: GetOAuthGrantForUpdate :one
+SELECT * FROM oauth_grants
+WHERE user_id = $1
+AND client_id = $2
+FOR UPDATE;
+
+-- name: GetUserOAuthGrants :many
+SELECT oauth_grants.client_id, oauth_clients.name AS client_name, oauth_grants.scopes,
+    oauth_grants.created_at, oauth_grants.updated_at
+FROM oauth_grants
+JOIN oauth_clients ON oauth_clients.id = oauth_grants.client_id
+WHERE oauth_grants.user_id = $1
+ORDER BY oauth_grants.created_at;
+
+-- name: DeleteOAuthGrant :execrows
+DELETE FROM oauth_grants
+WHERE user_id = $1
+AND client_id = $2;
+
+-- name: CreateOAuthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (
+    code_hash, created_at, expires_at, client_id, user_id, redirect_uri, scopes, code_challenge, used_at
+)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    NULL
+);
+
+-- name: GetOAuthAuthorizationCodeForUpdate :one
+SELECT * FROM oauth_authorization_codes
+WHERE code_hash = $1
+FOR UPDATE;
+
+-- name: UseOAuthAuthorizationCode :exec
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1;
+
+-- name: DeleteOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1
+AND client_id = $2;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at <= NOW();
+
+-- name: CreateOAuthAccessToken :exec
+INSERT INTO oauth_access_tokens (id, created_at, client_id, user_id)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3
+);
+
+-- name: GetOAuthGrantAccessTokens :many
+SELECT id, created_at FROM oauth_access_tokens
+WHERE user_id = $1
+AND client_id = $2
+AND created_at > $3;
+
+-- name: DeleteExpiredOAuthAccessTokens :exec
+DELETE FROM oauth_access_tokens
+WHERE created_at <= $1;
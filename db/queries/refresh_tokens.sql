@@ -1,12 +1,18 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, expires_at, revoked_at, user_id)
+INSERT INTO refresh_tokens (id, token_hash, created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, access_token_id)
 VALUES (
+    gen_random_uuid(),
     $1,
     NOW(),
     NOW(),
     $2,
     NULL,
-    $3
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7
 )
 RETURNING *;
 
@@ -19,9 +25,64 @@ ORDER BY created_at ASC;
 
 -- name: GetRefreshTokenByToken :one
 SELECT * FROM refresh_tokens
-WHERE token = $1;
+WHERE token_hash = $1;
 
--- name: RevokeRefreshToken :exec
+-- name: RevokeUserRefreshTokens :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(), updated_at = NOW()
-WHERE user_id = $1;
+WHERE user_id = $1
+AND revoked_at IS NULL;
+
+-- name: GetRefreshTokenByTokenForUpdate :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: RotateRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), last_used_at = NOW(), replaced_by = $2
+WHERE id = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenByToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE token_hash = $1
+AND revoked_at IS NULL;
+
+-- name: GetActiveSessions :many
+SELECT active.family_id,
+  (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = active.family_id)::timestamp AS created_at,
+  active.last_used_at,
+  active.user_agent,
+  active.ip_address,
+  active.expires_at
+FROM refresh_tokens active
+WHERE active.user_id = $1
+AND active.revoked_at IS NULL
+AND active.expires_at > NOW()
+ORDER BY active.last_used_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1
+AND family_id = $2
+AND revoked_at IS NULL;
+
+-- name: GetFamilyAccessTokens :many
+SELECT access_token_id, created_at FROM refresh_tokens
+WHERE family_id = $1
+AND access_token_id <> ''
+AND created_at > $2;
+
+-- name: GetUserAccessTokens :many
+SELECT access_token_id, created_at FROM refresh_tokens
+WHERE user_id = $1
+AND access_token_id <> ''
+AND created_at > $2;
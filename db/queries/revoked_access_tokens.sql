@@ -0,0 +1,19 @@
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (id, created_at, expires_at)
+VALUES (
+    $1,
+    NOW(),
+    $2
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsAccessTokenRevoked :one
+SELECT EXISTS (
+  SELECT 1 FROM revoked_access_tokens
+  WHERE id = $1
+  AND expires_at > NOW()
+);
+
+-- name: DeleteExpiredRevokedAccessTokens :exec
+DELETE FROM revoked_access_tokens
+WHERE expires_at <= NOW();
@@ -0,0 +1,23 @@
+-- name: CreateRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, changed_by, old_role, new_role, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetRoleChangesPage :many
+SELECT *
+FROM role_changes
+WHERE user_id = sqlc.arg('user_id')
+AND (
+  sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
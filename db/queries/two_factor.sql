@@ -0,0 +1,59 @@
+-- name: GetTOTPCredential :one
+SELECT * FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: GetTOTPCredentialForUpdate :one
+SELECT * FROM totp_credentials
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: UpsertPendingTOTPCredential :exec
+INSERT INTO totp_credentials (user_id, created_at, secret, confirmed_at, last_used_counter)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    NULL,
+    0
+)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = NOW(),
+    secret = EXCLUDED.secret,
+    confirmed_at = NULL,
+    last_used_counter = 0;
+
+-- name: ConfirmTOTPCredential :exec
+UPDATE totp_credentials
+SET confirmed_at = NOW(),
+    last_used_counter = $2
+WHERE user_id = $1;
+
+-- name: SetTOTPLastUsedCounter :exec
+UPDATE totp_credentials
+SET last_used_counter = $2
+WHERE user_id = $1;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, created_at, user_id, code_hash, used_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    NULL
+);
+
+-- name: GetUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE user_id = $1
+AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE id = $1
+AND used_at IS NULL;
@@ -22,7 +22,7 @@ FROM users
 WHERE id IN (
   SELECT user_id
   FROM refresh_tokens
-  WHERE token = $1
+  WHERE token_hash = $1
   AND revoked_at IS NULL
   AND expires_at > NOW()
 );
@@ -31,6 +31,7 @@ WHERE id IN (
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -41,3 +42,32 @@ SET is_chirpy_red = TRUE,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetEmailVerified :execrows
+UPDATE users
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND email = $2;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
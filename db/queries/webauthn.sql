@@ -0,0 +1,60 @@
+-- name: CreateWebAuthnSession :one
+INSERT INTO webauthn_sessions (id, created_at, expires_at, user_id, data)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: TakeWebAuthnSession :one
+DELETE FROM webauthn_sessions
+WHERE id = $1
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnSessions :exec
+DELETE FROM webauthn_sessions
+WHERE expires_at <= NOW();
+
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (
+    id, created_at, user_id, credential_id, public_key, attestation_type, aaguid,
+    transports, sign_count, clone_warning, backup_eligible, backup_state, last_used_at
+)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    FALSE,
+    $8,
+    $9,
+    NULL
+)
+RETURNING *;
+
+-- name: GetUserWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateWebAuthnCredentialUse :exec
+UPDATE webauthn_credentials
+SET sign_count = $2,
+    clone_warning = $3,
+    backup_state = $4,
+    last_used_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1
+AND user_id = $2;
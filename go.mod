@@ -9,8 +9,25 @@ require (
 
 require github.com/golang-jwt/jwt/v5 v5.3.0
 
+require (
+	github.com/coreos/go-oidc/v3 v3.18.0
+	golang.org/x/oauth2 v0.36.0
+	golang.org/x/text v0.30.0
+)
+
+require github.com/go-jose/go-jose/v4 v4.1.4 // indirect
+
+require (
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/go-webauthn/webauthn v0.15.0
+	github.com/go-webauthn/x v0.1.26 // indirect
+	github.com/google/go-tpm v0.9.6 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+)
+
 require (
 	github.com/alexedwards/argon2id v1.0.0
-	golang.org/x/crypto v0.14.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/crypto v0.43.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
 )
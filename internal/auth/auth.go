@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -15,57 +16,168 @@ import (
 
 const (
 	issuer = "chirpy"
+
+	// DefaultAudience is the audience of access tokens for the Chirpy API
+	DefaultAudience = "chirpy-api"
+	// EmailVerificationAudience is the audience of tokens emailed to users to verify their email address,
+	// so they can't be used as access tokens
+	EmailVerificationAudience = "chirpy-email-verification"
+	// LoginChallengeAudience is the audience of tokens issued for a correct password when the user
+	// has two-factor authentication, which only prove the first factor
+	LoginChallengeAudience = "chirpy-login-challenge"
+
+	// ClockSkewLeeway is how far a token's exp, nbf and iat can be out,
+	// to allow for clocks differing between Chirpy and other services that verify its tokens
+	ClockSkewLeeway = 30 * time.Second
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    issuer,
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+// Role is what a user is allowed to do. Each role can do everything the roles below it can.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether a user with role r may do what other allows.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+// Claims are the claims in a Chirpy access token.
+// Scope is a space separated list of scopes, as in RFC 8693.
+// ClientID is set on tokens issued to third-party apps through OAuth, as in RFC 9068.
+// UserID is parsed from the subject when the token is validated.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role     Role      `json:"role"`
+	Scope    string    `json:"scope,omitempty"`
+	ClientID string    `json:"client_id,omitempty"`
+	UserID   uuid.UUID `json:"-"`
+}
+
+// TokenOptions describe the access token made by MakeJWT.
+type TokenOptions struct {
+	UserID    uuid.UUID
+	Role      Role
+	ExpiresIn time.Duration
+	// Audience defaults to DefaultAudience
+	Audience []string
+	// NotBefore defaults to the time the token is issued
+	NotBefore time.Time
+	// ID is the jti claim, a random UUID by default
+	ID string
+	// Scopes limit what the token can be used for
+	Scopes []string
+	// ClientID is the OAuth client the token is issued to, if any
+	ClientID string
+}
+
+func MakeJWT(keyring *Keyring, opts TokenOptions) (string, error) {
+	now := time.Now().UTC()
+
+	audience := opts.Audience
+	if len(audience) == 0 {
+		audience = []string{DefaultAudience}
+	}
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = now
+	}
+	id := opts.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
 
-	signingKey := []byte(tokenSecret)
-	signed, err := token.SignedString(signingKey)
+	signed, err := keyring.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.ExpiresIn)),
+			NotBefore: jwt.NewNumericDate(notBefore),
+			Subject:   opts.UserID.String(),
+			Audience:  audience,
+			ID:        id,
+		},
+		Role:     opts.Role,
+		Scope:    strings.Join(opts.Scopes, " "),
+		ClientID: opts.ClientID,
+	})
 	if err != nil {
-		return "", fmt.Errorf("signing token string with secret: %v", err)
+		return "", fmt.Errorf("signing token string with key %q: %v", keyring.signing.ID, err)
 	}
 
 	return signed, nil
 }
 
-func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+func ValidateJWT(tokenString string, keyring *Keyring) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keyring)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+// ParseJWT validates an access token for the Chirpy API like ValidateJWT, returning all of its claims.
+// Tokens issued before roles existed are treated as belonging to a plain user.
+func ParseJWT(tokenString string, keyring *Keyring) (*Claims, error) {
+	return ParseJWTFor(tokenString, keyring, DefaultAudience)
+}
+
+// ParseJWTFor validates a token that must have been issued for the given audience.
+func ParseJWTFor(tokenString string, keyring *Keyring, audience string) (*Claims, error) {
+	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		claims,
-		func(t *jwt.Token) (any, error) {
-			return []byte(tokenSecret), nil
-		},
+		keyring.keyfunc,
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(ClockSkewLeeway),
+		jwt.WithIssuedAt(),
 	)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("parsing token with claims: %v", err)
+		return nil, fmt.Errorf("parsing token with claims: %v", err)
 	}
 
 	userID, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("getting token claims subject: %v", err)
+		return nil, fmt.Errorf("getting token claims subject: %v", err)
 	}
 
 	cIssuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 	if cIssuer != string(issuer) {
-		return uuid.Nil, errors.New("invalid issuer")
+		return nil, errors.New("invalid issuer")
 	}
 
-	userUUID, err := uuid.Parse(userID)
+	claims.UserID, err = uuid.Parse(userID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("converting string %v to UUID: %v", userID, err)
+		return nil, fmt.Errorf("converting string %v to UUID: %v", userID, err)
 	}
 
-	return userUUID, nil
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+	if !claims.Role.Valid() {
+		return nil, fmt.Errorf("invalid role %q", claims.Role)
+	}
+
+	return claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -104,3 +216,10 @@ func MakeRefreshToken() (string, error) {
 
 	return token, nil
 }
+
+// HashRefreshToken returns the hex SHA-256 digest of a refresh token, which is what is stored in the database.
+// Refresh tokens are random, so unlike passwords they don't need a slow, salted hash.
+func HashRefreshToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,7 +75,7 @@ func TestCheckPasswordHash(t *testing.T) {
 
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	validToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: RoleUser, ExpiresIn: time.Hour})
 
 	tests := []struct {
 		name        string
@@ -108,7 +109,7 @@ func TestValidateJWT(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, err := ValidateJWT(tt.tokenString, hmacKeyring(t, tt.tokenSecret))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -120,6 +121,206 @@ func TestValidateJWT(t *testing.T) {
 	}
 }
 
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+	adminToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: RoleAdmin, ExpiresIn: time.Hour})
+	noRoleToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: "", ExpiresIn: time.Hour})
+	badRoleToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: "superuser", ExpiresIn: time.Hour})
+
+	tests := []struct {
+		name        string
+		tokenString string
+		wantRole    Role
+		wantErr     bool
+	}{
+		{
+			name:        "Admin token",
+			tokenString: adminToken,
+			wantRole:    RoleAdmin,
+			wantErr:     false,
+		},
+		{
+			name:        "Token without a role",
+			tokenString: noRoleToken,
+			wantRole:    RoleUser,
+			wantErr:     false,
+		},
+		{
+			name:        "Token with an unknown role",
+			tokenString: badRoleToken,
+			wantRole:    "",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseJWT(tt.tokenString, hmacKeyring(t, "secret"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJWT() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if claims.Role != tt.wantRole {
+				t.Errorf("ParseJWT() Role = %v, want %v", claims.Role, tt.wantRole)
+			}
+			if claims.UserID != userID {
+				t.Errorf("ParseJWT() UserID = %v, want %v", claims.UserID, userID)
+			}
+		})
+	}
+}
+
+func TestParseJWTRegisteredClaims(t *testing.T) {
+	keyring := hmacKeyring(t, "secret")
+	userID := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		opts     TokenOptions
+		audience string
+		wantErr  bool
+	}{
+		{
+			name:     "Default audience",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: time.Hour},
+			audience: DefaultAudience,
+			wantErr:  false,
+		},
+		{
+			name:     "Other audience",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: time.Hour, Audience: []string{"other-service"}},
+			audience: DefaultAudience,
+			wantErr:  true,
+		},
+		{
+			name:     "Other audience checked for",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: time.Hour, Audience: []string{"other-service"}},
+			audience: "other-service",
+			wantErr:  false,
+		},
+		{
+			name:     "Not yet valid",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: time.Hour, NotBefore: now.Add(time.Minute)},
+			audience: DefaultAudience,
+			wantErr:  true,
+		},
+		{
+			name:     "Not yet valid within leeway",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: time.Hour, NotBefore: now.Add(ClockSkewLeeway / 2)},
+			audience: DefaultAudience,
+			wantErr:  false,
+		},
+		{
+			name:     "Expired within leeway",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: -ClockSkewLeeway / 2},
+			audience: DefaultAudience,
+			wantErr:  false,
+		},
+		{
+			name:     "Expired",
+			opts:     TokenOptions{UserID: userID, ExpiresIn: -time.Minute},
+			audience: DefaultAudience,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenString, err := MakeJWT(keyring, tt.opts)
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+			_, err = ParseJWTFor(tokenString, keyring, tt.audience)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJWTFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	clientID := uuid.NewString()
+	clientToken, _ := MakeJWT(keyring, TokenOptions{UserID: userID, ExpiresIn: time.Hour, ClientID: clientID})
+	if clientClaims, err := ParseJWT(clientToken, keyring); err != nil || clientClaims.ClientID != clientID {
+		t.Errorf("ParseJWT() client_id = %v, error = %v, want %q", clientClaims, err, clientID)
+	}
+
+	first, _ := MakeJWT(keyring, TokenOptions{UserID: userID, ExpiresIn: time.Hour})
+	second, _ := MakeJWT(keyring, TokenOptions{UserID: userID, ExpiresIn: time.Hour})
+	firstClaims, _ := ParseJWT(first, keyring)
+	secondClaims, _ := ParseJWT(second, keyring)
+	if firstClaims.ID == "" || firstClaims.ID == secondClaims.ID {
+		t.Errorf("MakeJWT() jti = %q and %q, want unique IDs", firstClaims.ID, secondClaims.ID)
+	}
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		check string
+		want  bool
+	}{
+		{
+			name:  "Granted scope",
+			scope: ScopeChirpsRead + " " + ScopeChirpsWrite,
+			check: ScopeChirpsWrite,
+			want:  true,
+		},
+		{
+			name:  "Missing scope",
+			scope: ScopeChirpsRead,
+			check: ScopeChirpsWrite,
+			want:  false,
+		},
+		{
+			name:  "Token without scopes",
+			scope: "",
+			check: ScopeAccountWrite,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := hmacKeyring(t, "secret")
+			tokenString, _ := MakeJWT(keyring, TokenOptions{UserID: uuid.New(), ExpiresIn: time.Hour, Scopes: strings.Fields(tt.scope)})
+			claims, err := ParseJWT(tokenString, keyring)
+			if err != nil {
+				t.Fatalf("ParseJWT() error = %v", err)
+			}
+			if got := claims.HasScope(tt.check); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.check, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleAdmin, RoleAdmin, true},
+		{RoleModerator, RoleUser, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{"superuser", RoleUser, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.other), func(t *testing.T) {
+			if got := tt.role.Includes(tt.other); got != tt.want {
+				t.Errorf("Role(%q).Includes(%q) = %v, want %v", tt.role, tt.other, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -164,3 +365,25 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Errorf("HashRefreshToken() returned the token unchanged")
+	}
+	if len(hash) != 64 {
+		t.Errorf("HashRefreshToken() length = %v, want 64", len(hash))
+	}
+	if again := HashRefreshToken(token); again != hash {
+		t.Errorf("HashRefreshToken() = %v, then %v, want the same digest", hash, again)
+	}
+	// Matches encode(sha256(convert_to(token, 'UTF8')), 'hex') in the migration that hashed existing tokens
+	if got, want := HashRefreshToken("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("HashRefreshToken(%q) = %v, want %v", "abc", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestSecretCipher(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	c, err := ParseSecretCipher(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("ParseSecretCipher() error = %v", err)
+	}
+
+	plaintext := []byte("12345678901234567890")
+	ciphertext, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Errorf("Encrypt() ciphertext contains the plaintext")
+	}
+
+	got, err := c.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := c.Decrypt(ciphertext); err == nil {
+		t.Errorf("Decrypt() accepted a tampered ciphertext")
+	}
+
+	if _, err := NewSecretCipher([]byte("too short")); err == nil {
+		t.Errorf("NewSecretCipher() accepted a short key")
+	}
+}
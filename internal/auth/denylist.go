@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+// Denylist records access tokens that were revoked before they expired, by their jti claim.
+// Access tokens are otherwise valid until they expire, so the denylist is what makes
+// logging out take effect immediately.
+type Denylist interface {
+	// Revoke denylists a token until expiresAt, after which it would be rejected anyway
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryDenylist is a Denylist held in memory, for running a single instance of Chirpy.
+// When it is full, the least recently revoked or checked token is forgotten,
+// and becomes valid again until it expires.
+type MemoryDenylist struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type denylistEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+func NewMemoryDenylist(capacity int) *MemoryDenylist {
+	return &MemoryDenylist{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (d *MemoryDenylist) Revoke(_ context.Context, id string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[id]; ok {
+		el.Value.(*denylistEntry).expiresAt = expiresAt
+		d.order.MoveToFront(el)
+		return nil
+	}
+
+	d.entries[id] = d.order.PushFront(&denylistEntry{id: id, expiresAt: expiresAt})
+	for d.order.Len() > d.capacity {
+		d.remove(d.order.Back())
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(_ context.Context, id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	el, ok := d.entries[id]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(el.Value.(*denylistEntry).expiresAt) {
+		d.remove(el)
+		return false, nil
+	}
+	d.order.MoveToFront(el)
+	return true, nil
+}
+
+func (d *MemoryDenylist) remove(el *list.Element) {
+	d.order.Remove(el)
+	delete(d.entries, el.Value.(*denylistEntry).id)
+}
+
+// PostgresDenylist is a Denylist stored in the revoked_access_tokens table,
+// shared by every instance of Chirpy using the same database.
+type PostgresDenylist struct {
+	db *database.Queries
+}
+
+func NewPostgresDenylist(db *database.Queries) *PostgresDenylist {
+	return &PostgresDenylist{db: db}
+}
+
+func (d *PostgresDenylist) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := d.db.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		ID:        id,
+		ExpiresAt: expiresAt.UTC(),
+	}); err != nil {
+		return fmt.Errorf("revoking access token: %v", err)
+	}
+	// Expired tokens are rejected anyway, so there is no need to keep them
+	if err := d.db.DeleteExpiredRevokedAccessTokens(ctx); err != nil {
+		return fmt.Errorf("deleting expired revoked access tokens: %v", err)
+	}
+	return nil
+}
+
+func (d *PostgresDenylist) IsRevoked(ctx context.Context, id string) (bool, error) {
+	revoked, err := d.db.IsAccessTokenRevoked(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("checking access token revocation: %v", err)
+	}
+	return revoked, nil
+}
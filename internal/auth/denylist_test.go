@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryDenylist(t *testing.T) {
+	ctx := context.Background()
+	d := NewMemoryDenylist(2)
+
+	_ = d.Revoke(ctx, "expired", time.Now().Add(-time.Minute))
+	_ = d.Revoke(ctx, "first", time.Now().Add(time.Hour))
+	_ = d.Revoke(ctx, "second", time.Now().Add(time.Hour))
+	// Checking first makes second the least recently used, so it is evicted by third
+	_, _ = d.IsRevoked(ctx, "first")
+	_ = d.Revoke(ctx, "third", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"expired", false},
+		{"first", true},
+		{"second", false},
+		{"third", true},
+		{"unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := d.IsRevoked(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("IsRevoked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms, as they appear in a token's alg header.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// DefaultKeyID is the ID of the key built from JWT_SECRET.
+// Tokens issued before keys had IDs have no kid header, and are checked against this key.
+const DefaultKeyID = "default"
+
+// Key is a JWT signing or verification key, identified by the kid header of the tokens it signs.
+// Asymmetric keys built from a public key alone can only verify tokens.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   any
+	verifyKey any
+}
+
+// NewHMACKey returns an HS256 key. The secret both signs and verifies tokens, so it must not be shared.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewEd25519Key returns an EdDSA key that signs with priv.
+func NewEd25519Key(id string, priv ed25519.PrivateKey) *Key {
+	return &Key{ID: id, Algorithm: AlgEdDSA, signKey: priv, verifyKey: priv.Public()}
+}
+
+// NewRSAKey returns an RS256 key that signs with priv.
+func NewRSAKey(id string, priv *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Algorithm: AlgRS256, signKey: priv, verifyKey: &priv.PublicKey}
+}
+
+// ParseKey builds a key from its algorithm and key material: the secret for HS256,
+// or a PEM encoded private or public key for EdDSA and RS256.
+func ParseKey(id string, alg string, material []byte) (*Key, error) {
+	switch alg {
+	case AlgHS256:
+		if len(material) == 0 {
+			return nil, errors.New("HS256 key has an empty secret")
+		}
+		return NewHMACKey(id, material), nil
+	case AlgEdDSA:
+		if priv, err := jwt.ParseEdPrivateKeyFromPEM(material); err == nil {
+			return NewEd25519Key(id, priv.(ed25519.PrivateKey)), nil
+		}
+		pub, err := jwt.ParseEdPublicKeyFromPEM(material)
+		if err != nil {
+			return nil, fmt.Errorf("parsing EdDSA key: %v", err)
+		}
+		return &Key{ID: id, Algorithm: alg, verifyKey: pub}, nil
+	case AlgRS256:
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(material); err == nil {
+			return NewRSAKey(id, priv), nil
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(material)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RS256 key: %v", err)
+		}
+		return &Key{ID: id, Algorithm: alg, verifyKey: pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// CanSign reports whether the key holds the secret or private key needed to sign tokens.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring holds the key that signs new tokens, and every key that tokens are accepted from.
+// Rotating keys means adding a new signing key while keeping the old one for verification
+// until the tokens it signed have expired.
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeyring returns a keyring that signs with signing, and verifies tokens from it and any of verification.
+func NewKeyring(signing *Key, verification ...*Key) (*Keyring, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("signing key must have a secret or private key")
+	}
+
+	k := &Keyring{signing: signing, keys: make(map[string]*Key, len(verification)+1)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+	return k, nil
+}
+
+// sign signs claims with the active signing key, setting the kid header.
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signing.signKey)
+}
+
+// keyfunc finds the key that signed a token by its kid header, for jwt.ParseWithClaims.
+// The token's algorithm must match the key's, so a public key can never be used as an HMAC secret.
+func (k *Keyring) keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("token algorithm %q doesn't match key %q", t.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format, as described in RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a set of public keys, served so other services can verify tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys in the keyring. HMAC keys are secret, so they are left out.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+	switch pub := k.verifyKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return jwk, jwk.KeyType != ""
+}
+
+// keyringFile is the format of the file named by JWT_KEYS_FILE.
+type keyringFile struct {
+	// Active is the ID of the key that signs new tokens
+	Active string `json:"active"`
+	Keys   []struct {
+		ID        string `json:"kid"`
+		Algorithm string `json:"alg"`
+		// Secret is the HS256 secret
+		Secret string `json:"secret"`
+		// PEMFile is the path to a PEM encoded EdDSA or RS256 private or public key
+		PEMFile string `json:"pem_file"`
+	} `json:"keys"`
+}
+
+// LoadKeyring builds a keyring from a JSON keys file, if path is set, and the legacy HS256 secret, if set.
+// The secret is only used to sign tokens when there is no keys file, and is otherwise kept
+// for verification, so tokens issued before the keys file was introduced stay valid.
+func LoadKeyring(path string, legacySecret string) (*Keyring, error) {
+	var legacy *Key
+	if legacySecret != "" {
+		legacy = NewHMACKey(DefaultKeyID, []byte(legacySecret))
+	}
+
+	if path == "" {
+		if legacy == nil {
+			return nil, errors.New("either a keys file or a secret must be set")
+		}
+		return NewKeyring(legacy)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keys file: %v", err)
+	}
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing keys file: %v", err)
+	}
+
+	var signing *Key
+	var verification []*Key
+	for _, entry := range file.Keys {
+		material := []byte(entry.Secret)
+		if entry.PEMFile != "" {
+			material, err = os.ReadFile(entry.PEMFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading key %q: %v", entry.ID, err)
+			}
+		}
+
+		key, err := ParseKey(entry.ID, entry.Algorithm, material)
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %q: %v", entry.ID, err)
+		}
+		if entry.ID == file.Active {
+			signing = key
+			continue
+		}
+		verification = append(verification, key)
+	}
+	if signing == nil {
+		return nil, fmt.Errorf("active key %q not found in keys file", file.Active)
+	}
+	if legacy != nil {
+		verification = append(verification, legacy)
+	}
+
+	return NewKeyring(signing, verification...)
+}
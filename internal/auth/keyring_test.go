@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// hmacKeyring returns a keyring with a single HS256 key, like the one built from JWT_SECRET.
+func hmacKeyring(t *testing.T, secret string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(NewHMACKey(DefaultKeyID, []byte(secret)))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	return NewEd25519Key(id, priv)
+}
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	return NewRSAKey(id, priv)
+}
+
+func TestKeyringAlgorithms(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{
+			name: "HS256",
+			key:  NewHMACKey("hs", []byte("secret")),
+		},
+		{
+			name: "EdDSA",
+			key:  newEd25519Key(t, "ed"),
+		},
+		{
+			name: "RS256",
+			key:  newRSAKey(t, "rs"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tt.key)
+			if err != nil {
+				t.Fatalf("NewKeyring() error = %v", err)
+			}
+
+			userID := uuid.New()
+			tokenString, err := MakeJWT(keyring, TokenOptions{UserID: userID, Role: RoleUser, ExpiresIn: time.Hour})
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified() error = %v", err)
+			}
+			if got := token.Header["kid"]; got != tt.key.ID {
+				t.Errorf("MakeJWT() kid = %v, want %v", got, tt.key.ID)
+			}
+			if got := token.Method.Alg(); got != tt.key.Algorithm {
+				t.Errorf("MakeJWT() alg = %v, want %v", got, tt.key.Algorithm)
+			}
+
+			gotUserID, err := ValidateJWT(tokenString, keyring)
+			if err != nil {
+				t.Fatalf("ValidateJWT() error = %v", err)
+			}
+			if gotUserID != userID {
+				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := NewHMACKey(DefaultKeyID, []byte("secret"))
+	newKey := newEd25519Key(t, "2026-01")
+
+	oldKeyring, _ := NewKeyring(oldKey)
+	rotatedKeyring, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	retiredKeyring, _ := NewKeyring(newKey)
+
+	oldToken, _ := MakeJWT(oldKeyring, TokenOptions{UserID: uuid.New(), Role: RoleUser, ExpiresIn: time.Hour})
+	newToken, _ := MakeJWT(rotatedKeyring, TokenOptions{UserID: uuid.New(), Role: RoleUser, ExpiresIn: time.Hour})
+
+	// Signed the way tokens were before keys had IDs
+	noKidToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Audience:  jwt.ClaimStrings{DefaultAudience},
+		Subject:   uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name        string
+		tokenString string
+		keyring     *Keyring
+		wantErr     bool
+	}{
+		{
+			name:        "Old token after rotation",
+			tokenString: oldToken,
+			keyring:     rotatedKeyring,
+			wantErr:     false,
+		},
+		{
+			name:        "New token after rotation",
+			tokenString: newToken,
+			keyring:     rotatedKeyring,
+			wantErr:     false,
+		},
+		{
+			name:        "Token without kid uses the default key",
+			tokenString: noKidToken,
+			keyring:     rotatedKeyring,
+			wantErr:     false,
+		},
+		{
+			name:        "Old token after its key is retired",
+			tokenString: oldToken,
+			keyring:     retiredKeyring,
+			wantErr:     true,
+		},
+		{
+			name:        "New token with unknown kid",
+			tokenString: newToken,
+			keyring:     oldKeyring,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateJWT(tt.tokenString, tt.keyring)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey := newRSAKey(t, "rs")
+	keyring, _ := NewKeyring(rsaKey)
+
+	// An attacker signs an HS256 token using the public key as the HMAC secret
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.verifyKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Role: RoleAdmin,
+	})
+	token.Header["kid"] = "rs"
+	forged, _ := token.SignedString(publicPEM)
+
+	if _, err := ValidateJWT(forged, keyring); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token for an RS256 key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	publicDER, _ := x509.MarshalPKIXPublicKey(pub)
+
+	tests := []struct {
+		name        string
+		alg         string
+		material    []byte
+		wantCanSign bool
+		wantErr     bool
+	}{
+		{
+			name:        "HS256 secret",
+			alg:         AlgHS256,
+			material:    []byte("secret"),
+			wantCanSign: true,
+		},
+		{
+			name:    "HS256 empty secret",
+			alg:     AlgHS256,
+			wantErr: true,
+		},
+		{
+			name:        "EdDSA private key",
+			alg:         AlgEdDSA,
+			material:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+			wantCanSign: true,
+		},
+		{
+			name:        "EdDSA public key",
+			alg:         AlgEdDSA,
+			material:    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+			wantCanSign: false,
+		},
+		{
+			name:     "EdDSA key given as RS256",
+			alg:      AlgRS256,
+			material: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+			wantErr:  true,
+		},
+		{
+			name:     "Unsupported algorithm",
+			alg:      "none",
+			material: []byte("secret"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey("kid", tt.alg, tt.material)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if key.CanSign() != tt.wantCanSign {
+				t.Errorf("ParseKey() CanSign = %v, want %v", key.CanSign(), tt.wantCanSign)
+			}
+		})
+	}
+
+	verifyOnly, _ := ParseKey("kid", AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	if _, err := NewKeyring(verifyOnly); err == nil {
+		t.Errorf("NewKeyring() accepted a signing key without a private key")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	edKey := newEd25519Key(t, "ed")
+	rsaKey := newRSAKey(t, "rs")
+	keyring, err := NewKeyring(edKey, rsaKey, NewHMACKey(DefaultKeyID, []byte("secret")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	set := keyring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS() returned %v keys, want 2 without the HMAC key", len(set.Keys))
+	}
+
+	ed, rs := set.Keys[0], set.Keys[1]
+	if ed.KeyID != "ed" || ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Algorithm != AlgEdDSA {
+		t.Errorf("JWKS() Ed25519 key = %+v", ed)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(ed.X)
+	if err != nil || !ed25519.PublicKey(x).Equal(edKey.verifyKey) {
+		t.Errorf("JWKS() Ed25519 x doesn't decode to the public key")
+	}
+	if rs.KeyID != "rs" || rs.KeyType != "RSA" || rs.Algorithm != AlgRS256 || rs.N == "" || rs.E != "AQAB" {
+		t.Errorf("JWKS() RSA key = %+v", rs)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pemPath := filepath.Join(dir, "2026-01.pem")
+	if err := os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keysPath := filepath.Join(dir, "keys.json")
+	keysFile := `{"active": "2026-01", "keys": [{"kid": "2026-01", "alg": "EdDSA", "pem_file": "` + pemPath + `"}]}`
+	if err := os.WriteFile(keysPath, []byte(keysFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	legacyToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: uuid.New(), Role: RoleUser, ExpiresIn: time.Hour})
+
+	keyring, err := LoadKeyring(keysPath, "secret")
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if keyring.signing.ID != "2026-01" {
+		t.Errorf("LoadKeyring() signing key = %v, want 2026-01", keyring.signing.ID)
+	}
+	if _, err := ValidateJWT(legacyToken, keyring); err != nil {
+		t.Errorf("ValidateJWT() rejected a token signed with JWT_SECRET: %v", err)
+	}
+
+	if _, err := LoadKeyring("", ""); err == nil {
+		t.Errorf("LoadKeyring() accepted neither a keys file nor a secret")
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type contextKey int
+
+const claimsKey contextKey = iota
+
+var errTokenRevoked = errors.New("access token has been revoked")
+
+// Middleware validates the access token on a request once, and stores its claims in the request context
+// for handlers to read with UserIDFromContext and ClaimsFromContext.
+// Tokens on the denylist are treated as invalid.
+type Middleware struct {
+	keyring  *Keyring
+	denylist Denylist
+}
+
+func NewMiddleware(keyring *Keyring, denylist Denylist) *Middleware {
+	return &Middleware{keyring: keyring, denylist: denylist}
+}
+
+// Required rejects requests without a valid access token with 401 Unauthorized.
+func (m *Middleware) Required(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := GetBearerToken(r.Header)
+		if err != nil {
+			respondUnauthorized(w, "", "failed to get access token from request header", err)
+			return
+		}
+
+		claims, err := m.parse(r.Context(), accessToken)
+		if err != nil {
+			respondUnauthorized(w, "invalid_token", "user JWT not authorised", err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// Optional lets requests without a valid access token through anonymously.
+func (m *Middleware) Optional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := GetBearerToken(r.Header)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := m.parse(r.Context(), accessToken)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// parse validates an access token, and checks it hasn't been revoked.
+// If the denylist can't be checked the token is rejected, rather than risk accepting a revoked one.
+func (m *Middleware) parse(ctx context.Context, accessToken string) (*Claims, error) {
+	claims, err := ParseJWT(accessToken, m.keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := m.denylist.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errTokenRevoked
+	}
+	return claims, nil
+}
+
+// WithClaims returns a copy of ctx holding the claims of an authenticated request.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFromContext returns the claims stored by Middleware, if the request was authenticated.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// UserIDFromContext returns the ID of the authenticated user, if the request was authenticated.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return claims.UserID, true
+}
+
+// respondUnauthorized responds with 401 and a WWW-Authenticate challenge, as described in RFC 6750.
+// errorCode is left out when the request had no token at all.
+func respondUnauthorized(w http.ResponseWriter, errorCode string, msg string, err error) {
+	if err != nil {
+		log.Println(err)
+	}
+
+	challenge := `Bearer realm="chirpy"`
+	if errorCode != "" {
+		challenge += `, error="` + errorCode + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
+		log.Printf("Error writing data: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	userID := uuid.New()
+	validToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: RoleUser, ExpiresIn: time.Hour})
+	wrongSecretToken, _ := MakeJWT(hmacKeyring(t, "wrong_secret"), TokenOptions{UserID: userID, Role: RoleUser, ExpiresIn: time.Hour})
+	revokedToken, _ := MakeJWT(hmacKeyring(t, "secret"), TokenOptions{UserID: userID, Role: RoleUser, ExpiresIn: time.Hour, ID: "revoked"})
+
+	denylist := NewMemoryDenylist(10)
+	if err := denylist.Revoke(context.Background(), "revoked", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		optional      bool
+		authHeader    string
+		wantStatus    int
+		wantChallenge string
+		wantUserID    uuid.UUID
+	}{
+		{
+			name:          "Required without token",
+			authHeader:    "",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chirpy"`,
+		},
+		{
+			name:          "Required with malformed header",
+			authHeader:    "Token " + validToken,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chirpy"`,
+		},
+		{
+			name:          "Required with invalid token",
+			authHeader:    "Bearer " + wrongSecretToken,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name:          "Required with revoked token",
+			authHeader:    "Bearer " + revokedToken,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name:       "Required with valid token",
+			authHeader: "Bearer " + validToken,
+			wantStatus: http.StatusOK,
+			wantUserID: userID,
+		},
+		{
+			name:       "Optional without token",
+			optional:   true,
+			authHeader: "",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Optional with invalid token",
+			optional:   true,
+			authHeader: "Bearer " + wrongSecretToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Optional with revoked token",
+			optional:   true,
+			authHeader: "Bearer " + revokedToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Optional with valid token",
+			optional:   true,
+			authHeader: "Bearer " + validToken,
+			wantStatus: http.StatusOK,
+			wantUserID: userID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID uuid.UUID
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = UserIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			m := NewMiddleware(hmacKeyring(t, "secret"), denylist)
+			handler := m.Required(next)
+			if tt.optional {
+				handler = m.Optional(next)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Middleware status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("Middleware WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("UserIDFromContext() = %v, want %v", gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider, which issues one code for one ID token.
+type mockOIDCServer struct {
+	*httptest.Server
+	keyring       *Keyring
+	code          string
+	codeChallenge string
+	idToken       string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+	keyring, err := NewKeyring(newRSAKey(t, "mock"))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	m := &mockOIDCServer{keyring: keyring, code: "mock-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]any{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{AlgRS256},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, m.keyring.JWKS())
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil ||
+			r.PostForm.Get("code") != m.code ||
+			!VerifyPKCE(r.PostForm.Get("code_verifier"), m.codeChallenge) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeMockJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeMockJSON(w, map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func writeMockJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	const clientID, nonce, verifier = "chirpy", "mock-nonce", "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	server := newMockOIDCServer(t)
+	provider, err := NewOIDCProvider(context.Background(), server.URL, clientID, "secret", "http://localhost:8080/oidc/callback")
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+
+	authURL, err := url.Parse(provider.AuthCodeURL("mock-state", nonce, verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() isn't a URL: %v", err)
+	}
+	query := authURL.Query()
+	if query.Get("state") != "mock-state" || query.Get("nonce") != nonce || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("AuthCodeURL() = %v, want state, nonce and S256 code challenge", authURL)
+	}
+	server.codeChallenge = query.Get("code_challenge")
+
+	otherKeyring, err := NewKeyring(newRSAKey(t, "mock"))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            server.URL,
+			"sub":            "user-123",
+			"aud":            clientID,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          "user@example.com",
+			"email_verified": true,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(claims jwt.MapClaims)
+		keyring  *Keyring
+		verifier string
+		want     OIDCIdentity
+		wantErr  bool
+	}{
+		{
+			name:   "valid ID token",
+			modify: func(claims jwt.MapClaims) {},
+			want:   OIDCIdentity{Issuer: server.URL, Subject: "user-123", Email: "user@example.com", EmailVerified: true},
+		},
+		{
+			name:   "unverified email",
+			modify: func(claims jwt.MapClaims) { claims["email_verified"] = false },
+			want:   OIDCIdentity{Issuer: server.URL, Subject: "user-123", Email: "user@example.com"},
+		},
+		{
+			name:    "other nonce",
+			modify:  func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" },
+			wantErr: true,
+		},
+		{
+			name:    "other audience",
+			modify:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			wantErr: true,
+		},
+		{
+			name:    "other issuer",
+			modify:  func(claims jwt.MapClaims) { claims["iss"] = "https://idp.example.com" },
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			modify:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: true,
+		},
+		{
+			name:    "signed by a key not in the JWKS",
+			modify:  func(claims jwt.MapClaims) {},
+			keyring: otherKeyring,
+			wantErr: true,
+		},
+		{
+			name:     "wrong PKCE verifier",
+			modify:   func(claims jwt.MapClaims) {},
+			verifier: "aBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+			keyring := server.keyring
+			if tt.keyring != nil {
+				keyring = tt.keyring
+			}
+			server.idToken, err = keyring.sign(claims)
+			if err != nil {
+				t.Fatalf("sign() error = %v", err)
+			}
+
+			exchangeVerifier := verifier
+			if tt.verifier != "" {
+				exchangeVerifier = tt.verifier
+			}
+			got, err := provider.Exchange(context.Background(), server.code, exchangeVerifier, nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exchange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Exchange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
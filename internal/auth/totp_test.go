@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := TOTPCode(secret, TOTPCounter(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("TOTPCode() at %v = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	counter := TOTPCounter(now)
+
+	tests := []struct {
+		name        string
+		code        string
+		wantCounter int64
+		wantOK      bool
+	}{
+		{
+			name:        "Current code",
+			code:        TOTPCode(secret, counter),
+			wantCounter: counter,
+			wantOK:      true,
+		},
+		{
+			name:        "Previous code",
+			code:        TOTPCode(secret, counter-1),
+			wantCounter: counter - 1,
+			wantOK:      true,
+		},
+		{
+			name:        "Next code",
+			code:        TOTPCode(secret, counter+1),
+			wantCounter: counter + 1,
+			wantOK:      true,
+		},
+		{
+			name:   "Code from too long ago",
+			code:   TOTPCode(secret, counter-2),
+			wantOK: false,
+		},
+		{
+			name:   "Wrong length",
+			code:   "12345",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCounter, gotOK := ValidateTOTP(secret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if gotOK && gotCounter != tt.wantCounter {
+				t.Errorf("ValidateTOTP() counter = %v, want %v", gotCounter, tt.wantCounter)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("user@example.com", []byte("12345678901234567890")))
+	if err != nil {
+		t.Fatalf("TOTPURI() isn't a valid URL: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/chirpy:user@example.com" {
+		t.Errorf("TOTPURI() = %v", uri)
+	}
+	if got := uri.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("TOTPURI() secret = %v", got)
+	}
+}
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCode() error = %v", err)
+	}
+	if len(code) != 17 || code[8] != '-' || code != strings.ToLower(code) {
+		t.Errorf("GenerateRecoveryCode() = %q", code)
+	}
+	if got := NormaliseRecoveryCode(" " + strings.ToUpper(code) + " "); got != code {
+		t.Errorf("NormaliseRecoveryCode() = %q, want %q", got, code)
+	}
+}
@@ -7,29 +7,107 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasQuotes = `-- name: ChirpHasQuotes :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps
+  WHERE quote_of_id = $1
+)
+`
+
+func (q *Queries) ChirpHasQuotes(ctx context.Context, quoteOfID uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasQuotes, quoteOfID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps
+  WHERE parent_chirp_id = $1
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, parentChirpID uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, parentChirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ParentChirpID uuid.NullUUID `json:"parent_chirp_id"`
+	QuoteOfID     uuid.NullUUID `json:"quote_of_id"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentChirpID,
+		arg.QuoteOfID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.RepostOfID,
+		&i.QuoteOfID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, repost_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
+`
+
+type CreateRechirpParams struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	RepostOfID uuid.NullUUID `json:"repost_of_id"`
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RepostOfID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +115,12 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.RepostOfID,
+		&i.QuoteOfID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -51,8 +135,37 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id = $1
+AND repost_of_id = $2
+`
+
+type DeleteRechirpParams struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	RepostOfID uuid.NullUUID `json:"repost_of_id"`
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RepostOfID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE repost_of_id = $1
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, repostOfID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, repostOfID)
+	return err
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at FROM chirps
 WHERE id = $1
 ORDER BY created_at ASC
 `
@@ -66,17 +179,117 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.RepostOfID,
+		&i.QuoteOfID,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+const getChirpByIDForUpdate = `-- name: GetChirpByIDForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at FROM chirps
+WHERE id = $1
+FOR UPDATE
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirpByIDForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.RepostOfID,
+		&i.QuoteOfID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpThread = `-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+  SELECT chirps.id, chirps.parent_chirp_id
+  FROM chirps
+  WHERE chirps.id = $1
+  UNION ALL
+  SELECT parent.id, parent.parent_chirp_id
+  FROM chirps parent
+  JOIN ancestors ON parent.id = ancestors.parent_chirp_id
+),
+thread AS (
+  SELECT root.id, root.created_at, root.updated_at, root.body, root.user_id, root.parent_chirp_id, root.deleted_at, root.hidden_at, 0 AS depth
+  FROM chirps root
+  WHERE root.id = (SELECT ancestors.id FROM ancestors WHERE ancestors.parent_chirp_id IS NULL)
+  UNION ALL
+  SELECT reply.id, reply.created_at, reply.updated_at, reply.body, reply.user_id, reply.parent_chirp_id, reply.deleted_at, reply.hidden_at, thread.depth + 1
+  FROM chirps reply
+  JOIN thread ON reply.parent_chirp_id = thread.id
+)
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, hidden_at, depth
+FROM thread
+ORDER BY depth ASC, created_at ASC
+`
+
+type GetChirpThreadRow struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ParentChirpID uuid.NullUUID `json:"parent_chirp_id"`
+	DeletedAt     sql.NullTime  `json:"deleted_at"`
+	HiddenAt      sql.NullTime  `json:"hidden_at"`
+	Depth         int32         `json:"depth"`
+}
+
+func (q *Queries) GetChirpThread(ctx context.Context, id uuid.UUID) ([]GetChirpThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpThread, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpThreadRow
+	for rows.Next() {
+		var i GetChirpThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +303,12 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.RepostOfID,
+			&i.QuoteOfID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -104,15 +323,36 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id
+const getChirpsPageAsc = `-- name: GetChirpsPageAsc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = $1::uuid)
+AND ($2::uuid IS NULL OR user_id = $2::uuid)
+AND (
+  $3::timestamp IS NULL
+  OR (created_at, id) > ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type GetChirpsPageAscParams struct {
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	AuthorID        uuid.NullUUID `json:"author_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	PageSize        int32         `json:"page_size"`
+}
+
+func (q *Queries) GetChirpsPageAsc(ctx context.Context, arg GetChirpsPageAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageAsc,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -126,6 +366,12 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.RepostOfID,
+			&i.QuoteOfID,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -140,6 +386,145 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	return items, nil
 }
 
+const getChirpsPageDesc = `-- name: GetChirpsPageDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = $1::uuid)
+AND ($2::uuid IS NULL OR user_id = $2::uuid)
+AND (
+  $3::timestamp IS NULL
+  OR (created_at, id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type GetChirpsPageDescParams struct {
+	ViewerID        uuid.NullUUID `json:"viewer_id"`
+	AuthorID        uuid.NullUUID `json:"author_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	PageSize        int32         `json:"page_size"`
+}
+
+func (q *Queries) GetChirpsPageDesc(ctx context.Context, arg GetChirpsPageDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageDesc,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.RepostOfID,
+			&i.QuoteOfID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelinePage = `-- name: GetTimelinePage :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+AND (hidden_at IS NULL OR user_id = $1)
+AND (
+  user_id = $1
+  OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = $1)
+)
+AND (
+  $2::timestamp IS NULL
+  OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetTimelinePageParams struct {
+	UserID          uuid.UUID     `json:"user_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID `json:"cursor_id"`
+	PageSize        int32         `json:"page_size"`
+}
+
+func (q *Queries) GetTimelinePage(ctx context.Context, arg GetTimelinePageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimelinePage,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.RepostOfID,
+			&i.QuoteOfID,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE FROM chirps
 `
@@ -148,3 +533,134 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_chirp_id, chirps.deleted_at, chirps.repost_of_id, chirps.quote_of_id, chirps.hidden_at, ts_rank(chirps.search_vector, to_tsquery('english', $1)) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', $1)
+AND chirps.deleted_at IS NULL
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2::uuid)
+AND ($3::uuid IS NULL OR chirps.user_id = $3::uuid)
+ORDER BY rank DESC, chirps.created_at DESC
+LIMIT $4
+`
+
+type SearchChirpsParams struct {
+	Query    string        `json:"query"`
+	ViewerID uuid.NullUUID `json:"viewer_id"`
+	AuthorID uuid.NullUUID `json:"author_id"`
+	PageSize int32         `json:"page_size"`
+}
+
+type SearchChirpsRow struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	SearchVector  interface{}   `json:"-"`
+	ParentChirpID uuid.NullUUID `json:"parent_chirp_id"`
+	DeletedAt     sql.NullTime  `json:"-"`
+	RepostOfID    uuid.NullUUID `json:"repost_of_id"`
+	QuoteOfID     uuid.NullUUID `json:"quote_of_id"`
+	HiddenAt      sql.NullTime  `json:"-"`
+	Rank          float32       `json:"rank"`
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.RepostOfID,
+			&i.QuoteOfID,
+			&i.HiddenAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const unhideChirp = `-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unhideChirp, id)
+	return err
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_chirp_id, deleted_at, repost_of_id, quote_of_id, hidden_at
+`
+
+type UpdateChirpParams struct {
+	ID   uuid.UUID `json:"id"`
+	Body string    `json:"body"`
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.RepostOfID,
+		&i.QuoteOfID,
+		&i.HiddenAt,
+	)
+	return i, err
+}
@@ -6,33 +6,239 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Body          string        `json:"body"`
+	UserID        uuid.UUID     `json:"user_id"`
+	SearchVector  interface{}   `json:"-"`
+	ParentChirpID uuid.NullUUID `json:"parent_chirp_id"`
+	DeletedAt     sql.NullTime  `json:"-"`
+	RepostOfID    uuid.NullUUID `json:"repost_of_id"`
+	QuoteOfID     uuid.NullUUID `json:"quote_of_id"`
+	HiddenAt      sql.NullTime  `json:"-"`
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpLike struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
 	Body      string    `json:"body"`
+}
+
+type EmailVerification struct {
+	ID        uuid.UUID    `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Email     string       `json:"email"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	Kind         string    `json:"kind"`
+	Subject      string    `json:"subject"`
+	Failures     int32     `json:"failures"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+}
+
+type ModerationQueue struct {
+	ID             uuid.UUID      `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	ChirpID        uuid.NullUUID  `json:"chirp_id"`
+	ChirpBody      string         `json:"chirp_body"`
+	Source         string         `json:"source"`
+	ReporterID     uuid.NullUUID  `json:"reporter_id"`
+	Reason         string         `json:"reason"`
+	Status         string         `json:"status"`
+	DecidedBy      uuid.NullUUID  `json:"decided_by"`
+	DecidedAt      sql.NullTime   `json:"decided_at"`
+	DecisionReason sql.NullString `json:"decision_reason"`
+}
+
+type ModerationTerm struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	List      string    `json:"list"`
+	Term      string    `json:"term"`
+}
+
+type OauthAccessToken struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ClientID  uuid.UUID `json:"client_id"`
 	UserID    uuid.UUID `json:"user_id"`
 }
 
-type RefreshToken struct {
-	Token     string       `json:"token"`
+type OauthAuthorizationCode struct {
+	CodeHash      string       `json:"code_hash"`
+	CreatedAt     time.Time    `json:"created_at"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	ClientID      uuid.UUID    `json:"client_id"`
+	UserID        uuid.UUID    `json:"user_id"`
+	RedirectUri   string       `json:"redirect_uri"`
+	Scopes        []string     `json:"scopes"`
+	CodeChallenge string       `json:"code_challenge"`
+	UsedAt        sql.NullTime `json:"used_at"`
+}
+
+type OauthClient struct {
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	Name         string         `json:"name"`
+	SecretHash   sql.NullString `json:"secret_hash"`
+	RedirectUris []string       `json:"redirect_uris"`
+}
+
+type OauthGrant struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ClientID  uuid.UUID `json:"client_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Scopes    []string  `json:"scopes"`
+}
+
+type OidcLogin struct {
+	State        string    `json:"state"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	BindingHash  string    `json:"binding_hash"`
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID    `json:"id"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
 	ExpiresAt time.Time    `json:"expires_at"`
-	RevokedAt sql.NullTime `json:"revoked_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
 	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type RefreshToken struct {
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	RevokedAt     sql.NullTime  `json:"revoked_at"`
+	UserID        uuid.UUID     `json:"user_id"`
+	FamilyID      uuid.UUID     `json:"family_id"`
+	UserAgent     string        `json:"user_agent"`
+	IpAddress     string        `json:"ip_address"`
+	LastUsedAt    time.Time     `json:"last_used_at"`
+	ID            uuid.UUID     `json:"id"`
+	TokenHash     string        `json:"token_hash"`
+	ReplacedBy    uuid.NullUUID `json:"replaced_by"`
+	AccessTokenID string        `json:"access_token_id"`
+}
+
+type RevokedAccessToken struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RoleChange struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UserID    uuid.UUID     `json:"user_id"`
+	ChangedBy uuid.NullUUID `json:"changed_by"`
+	OldRole   string        `json:"old_role"`
+	NewRole   string        `json:"new_role"`
+	Reason    string        `json:"reason"`
+}
+
+type TotpCredential struct {
+	UserID          uuid.UUID    `json:"user_id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	Secret          []byte       `json:"secret"`
+	ConfirmedAt     sql.NullTime `json:"confirmed_at"`
+	LastUsedCounter int64        `json:"last_used_counter"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID              uuid.UUID    `json:"id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Email           string       `json:"email"`
+	HashedPassword  string       `json:"hashed_password"`
+	IsChirpyRed     bool         `json:"is_chirpy_red"`
+	Role            string       `json:"role"`
+	EmailVerifiedAt sql.NullTime `json:"-"`
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+}
+
+type WebauthnCredential struct {
+	ID              uuid.UUID    `json:"id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UserID          uuid.UUID    `json:"user_id"`
+	CredentialID    []byte       `json:"credential_id"`
+	PublicKey       []byte       `json:"public_key"`
+	AttestationType string       `json:"attestation_type"`
+	Aaguid          []byte       `json:"aaguid"`
+	Transports      []string     `json:"transports"`
+	SignCount       int64        `json:"sign_count"`
+	CloneWarning    bool         `json:"clone_warning"`
+	BackupEligible  bool         `json:"backup_eligible"`
+	BackupState     bool         `json:"backup_state"`
+	LastUsedAt      sql.NullTime `json:"last_used_at"`
+}
+
+type WebauthnSession struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	UserID    uuid.NullUUID   `json:"user_id"`
+	Data      json.RawMessage `json:"data"`
 }
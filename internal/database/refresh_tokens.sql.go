@@ -13,59 +13,214 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, expires_at, revoked_at, user_id)
+INSERT INTO refresh_tokens (id, token_hash, created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, access_token_id)
 VALUES (
+    gen_random_uuid(),
     $1,
     NOW(),
     NOW(),
     $2,
     NULL,
-    $3
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7
 )
-RETURNING token, created_at, updated_at, expires_at, revoked_at, user_id
+RETURNING created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, id, token_hash, replaced_by, access_token_id
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	UserID    uuid.UUID `json:"user_id"`
+	TokenHash     string    `json:"token_hash"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	UserID        uuid.UUID `json:"user_id"`
+	FamilyID      uuid.UUID `json:"family_id"`
+	UserAgent     string    `json:"user_agent"`
+	IpAddress     string    `json:"ip_address"`
+	AccessTokenID string    `json:"access_token_id"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.ExpiresAt, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.ExpiresAt,
+		arg.UserID,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.AccessTokenID,
+	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
 		&i.UserID,
+		&i.FamilyID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ID,
+		&i.TokenHash,
+		&i.ReplacedBy,
+		&i.AccessTokenID,
 	)
 	return i, err
 }
 
+const getActiveSessions = `-- name: GetActiveSessions :many
+SELECT active.family_id,
+  (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = active.family_id)::timestamp AS created_at,
+  active.last_used_at,
+  active.user_agent,
+  active.ip_address,
+  active.expires_at
+FROM refresh_tokens active
+WHERE active.user_id = $1
+AND active.revoked_at IS NULL
+AND active.expires_at > NOW()
+ORDER BY active.last_used_at DESC
+`
+
+type GetActiveSessionsRow struct {
+	FamilyID   uuid.UUID `json:"family_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) GetActiveSessions(ctx context.Context, userID uuid.UUID) ([]GetActiveSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSessionsRow
+	for rows.Next() {
+		var i GetActiveSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFamilyAccessTokens = `-- name: GetFamilyAccessTokens :many
+SELECT access_token_id, created_at FROM refresh_tokens
+WHERE family_id = $1
+AND access_token_id <> ''
+AND created_at > $2
+`
+
+type GetFamilyAccessTokensParams struct {
+	FamilyID  uuid.UUID `json:"family_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetFamilyAccessTokensRow struct {
+	AccessTokenID string    `json:"access_token_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (q *Queries) GetFamilyAccessTokens(ctx context.Context, arg GetFamilyAccessTokensParams) ([]GetFamilyAccessTokensRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFamilyAccessTokens, arg.FamilyID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFamilyAccessTokensRow
+	for rows.Next() {
+		var i GetFamilyAccessTokensRow
+		if err := rows.Scan(
+			&i.AccessTokenID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRefreshTokenByToken = `-- name: GetRefreshTokenByToken :one
-SELECT token, created_at, updated_at, expires_at, revoked_at, user_id FROM refresh_tokens
-WHERE token = $1
+SELECT created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, id, token_hash, replaced_by, access_token_id FROM refresh_tokens
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetRefreshTokenByToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenByToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.UserID,
+		&i.FamilyID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ID,
+		&i.TokenHash,
+		&i.ReplacedBy,
+		&i.AccessTokenID,
+	)
+	return i, err
+}
+
+const getRefreshTokenByTokenForUpdate = `-- name: GetRefreshTokenByTokenForUpdate :one
+SELECT created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, id, token_hash, replaced_by, access_token_id FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE
 `
 
-func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshTokenByToken, token)
+func (q *Queries) GetRefreshTokenByTokenForUpdate(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenByTokenForUpdate, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
 		&i.UserID,
+		&i.FamilyID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ID,
+		&i.TokenHash,
+		&i.ReplacedBy,
+		&i.AccessTokenID,
 	)
 	return i, err
 }
 
 const getRefreshTokens = `-- name: GetRefreshTokens :many
-SELECT token, created_at, updated_at, expires_at, revoked_at, user_id FROM refresh_tokens
+SELECT created_at, updated_at, expires_at, revoked_at, user_id, family_id, user_agent, ip_address, last_used_at, id, token_hash, replaced_by, access_token_id FROM refresh_tokens
 ORDER BY created_at ASC
 `
 
@@ -79,12 +234,62 @@ func (q *Queries) GetRefreshTokens(ctx context.Context) ([]RefreshToken, error)
 	for rows.Next() {
 		var i RefreshToken
 		if err := rows.Scan(
-			&i.Token,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ExpiresAt,
 			&i.RevokedAt,
 			&i.UserID,
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
+			&i.ID,
+			&i.TokenHash,
+			&i.ReplacedBy,
+			&i.AccessTokenID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAccessTokens = `-- name: GetUserAccessTokens :many
+SELECT access_token_id, created_at FROM refresh_tokens
+WHERE user_id = $1
+AND access_token_id <> ''
+AND created_at > $2
+`
+
+type GetUserAccessTokensParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetUserAccessTokensRow struct {
+	AccessTokenID string    `json:"access_token_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (q *Queries) GetUserAccessTokens(ctx context.Context, arg GetUserAccessTokensParams) ([]GetUserAccessTokensRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAccessTokens, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserAccessTokensRow
+	for rows.Next() {
+		var i GetUserAccessTokensRow
+		if err := rows.Scan(
+			&i.AccessTokenID,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -108,13 +313,78 @@ func (q *Queries) ResetRefreshTokens(ctx context.Context) error {
 	return err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const revokeRefreshTokenByToken = `-- name: RevokeRefreshTokenByToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE token_hash = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenByToken(ctx context.Context, tokenHash string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenByToken, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
 UPDATE refresh_tokens
 SET revoked_at = NOW(), updated_at = NOW()
 WHERE user_id = $1
+AND family_id = $2
+AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, userID uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, userID)
+type RevokeSessionParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), last_used_at = NOW(), replaced_by = $2
+WHERE id = $1
+`
+
+type RotateRefreshTokenParams struct {
+	ID         uuid.UUID     `json:"id"`
+	ReplacedBy uuid.NullUUID `json:"replaced_by"`
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, rotateRefreshToken, arg.ID, arg.ReplacedBy)
 	return err
 }
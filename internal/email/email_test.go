@@ -0,0 +1,68 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerFormat(t *testing.T) {
+	m := NewSMTPMailer("localhost:25", "chirpy@example.com", "", "")
+	date := time.Date(2026, 1, 20, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		msg     Message
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Plain message",
+			msg:  Message{To: "user@example.com", Subject: "Hello", Body: "Line one\nLine two"},
+			want: "From: chirpy@example.com\r\n" +
+				"To: user@example.com\r\n" +
+				"Subject: Hello\r\n" +
+				"Date: Tue, 20 Jan 2026 09:30:00 +0000\r\n" +
+				"MIME-Version: 1.0\r\n" +
+				"Content-Type: text/plain; charset=UTF-8\r\n" +
+				"\r\n" +
+				"Line one\r\nLine two",
+		},
+		{
+			name:    "Newline in subject",
+			msg:     Message{To: "user@example.com", Subject: "Hello\r\nBcc: victim@example.com", Body: "Hi"},
+			wantErr: true,
+		},
+		{
+			name:    "Newline in recipient",
+			msg:     Message{To: "user@example.com\nBcc: victim@example.com", Subject: "Hello", Body: "Hi"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.format(tt.msg, date)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("format() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf)
+
+	if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "To: user@example.com") || !strings.Contains(got, "Hi") {
+		t.Errorf("Send() wrote %q", got)
+	}
+}
@@ -2,25 +2,78 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"sync/atomic"
 
+	"github.com/corygyarmathy/chirpy/internal/auth"
 	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+	"github.com/corygyarmathy/chirpy/internal/moderation"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type API struct {
 	FileserverHits atomic.Int32
 	DB             *database.Queries
+	db             *sql.DB
 	platform       string
-	jwtSecret      string
+	keyring        *auth.Keyring
+	denylist       auth.Denylist
 	polkaKey       string
+	moderator      *moderation.Pipeline
+	mailer         email.Mailer
+	logins         *loginLimiter
+	resets         *loginLimiter
+	totpCipher     *auth.SecretCipher
+	passkeys       *webauthn.WebAuthn
+	// oidc is nil unless login through an OpenID Connect provider is configured
+	oidc *auth.OIDCProvider
+	// requireVerifiedEmail stops users posting chirps until they have verified their email address
+	requireVerifiedEmail bool
 }
 
-func New(db *database.Queries, platform string, jwtSecret string, polkaKey string) *API {
-	return &API{
+func New(db *sql.DB, platform string, keyring *auth.Keyring, denylist auth.Denylist, polkaKey string, moderator *moderation.Pipeline, mailer email.Mailer, requireVerifiedEmail bool, totpCipher *auth.SecretCipher, passkeys *webauthn.WebAuthn, oidc *auth.OIDCProvider) *API {
+	api := &API{
 		FileserverHits: atomic.Int32{},
-		DB:             db,
+		DB:             database.New(db),
+		db:             db,
 		platform:       platform,
-		jwtSecret:      jwtSecret,
+		keyring:        keyring,
+		denylist:       denylist,
 		polkaKey:       polkaKey,
+		moderator:      moderator,
+		mailer:         mailer,
+		totpCipher:     totpCipher,
+		passkeys:       passkeys,
+		oidc:           oidc,
+
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
+	api.logins = newLoginLimiter(api.DB, api.withTx)
+	api.resets = newPasswordResetLimiter(api.DB, api.withTx)
+	return api
+}
+
+// withTx runs fn with queries bound to a single transaction.
+// The transaction is committed if fn returns nil, and rolled back otherwise.
+func (api *API) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := api.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %v", err)
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(api.DB.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %v", err)
 	}
+	return nil
 }
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+	"github.com/corygyarmathy/chirpy/internal/moderation"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const testPassword = "correct horse battery staple"
+
+// newTestAPI returns an API using the database in TEST_DB_URL, which must have every migration applied.
+// Tests that need a database are skipped when it isn't set. Each test makes its own users,
+// so tests can share the database, and don't need it to be empty.
+func newTestAPI(t *testing.T) (*API, *testMailer) {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL isn't set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	keyring, err := auth.NewKeyring(auth.NewHMACKey("test", []byte("test-secret")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	totpCipher, err := auth.NewSecretCipher(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewSecretCipher() error = %v", err)
+	}
+
+	mailer := &testMailer{}
+	api := New(db, "dev", keyring, auth.NewMemoryDenylist(1000), "test-polka-key", moderation.NewPipeline(), mailer, true, totpCipher, nil, nil)
+	return api, mailer
+}
+
+// testMailer records the emails sent through it.
+type testMailer struct {
+	mu   sync.Mutex
+	sent []email.Message
+}
+
+func (m *testMailer) Send(_ context.Context, msg email.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// sentTo returns the emails sent to an address.
+func (m *testMailer) sentTo(address string) []email.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sent []email.Message
+	for _, msg := range m.sent {
+		if msg.To == address {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+// createTestUser creates a user with a unique email address and testPassword, verifying their email if asked.
+func createTestUser(t *testing.T, api *API, verified bool) database.User {
+	t.Helper()
+	hashedPassword, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	address := uuid.NewString() + "@example.com"
+	user, err := api.DB.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          address,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if verified {
+		if _, err := api.DB.SetEmailVerified(context.Background(), database.SetEmailVerifiedParams{
+			ID:    user.ID,
+			Email: address,
+		}); err != nil {
+			t.Fatalf("SetEmailVerified() error = %v", err)
+		}
+	}
+	return user
+}
+
+// newTestRequest makes a request with body encoded as JSON, if it isn't nil.
+func newTestRequest(t *testing.T, method string, target string, body any) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+	}
+	return httptest.NewRequest(method, target, &buf)
+}
+
+// asUser authenticates a request as the given user, as auth.Middleware would for their access token.
+func asUser(r *http.Request, user database.User) *http.Request {
+	return r.WithContext(auth.WithClaims(r.Context(), &auth.Claims{
+		UserID: user.ID,
+		Role:   auth.Role(user.Role),
+		Scope:  strings.Join(auth.AllScopes, " "),
+	}))
+}
+
+// serve runs a handler on a request, failing the test unless it responds with wantStatus.
+func serve(t *testing.T, handler http.HandlerFunc, r *http.Request, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d: %s", r.Method, r.URL, w.Code, wantStatus, w.Body)
+	}
+	return w
+}
+
+// decodeResponse decodes a JSON response body.
+func decodeResponse[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(w.Body).Decode(&v); err != nil {
+		t.Fatalf("decoding response body: %v", err)
+	}
+	return v
+}
@@ -1,19 +1,167 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"slices"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/corygyarmathy/chirpy/internal/auth"
 	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/moderation"
 	"github.com/google/uuid"
 )
 
+var (
+	// errParentChirpNotFound is returned when a reply's parent chirp doesn't exist.
+	errParentChirpNotFound = errors.New("parent chirp not found")
+	// errParentChirpDeleted is returned when a reply's parent chirp has been deleted, or hidden from the user.
+	errParentChirpDeleted = errors.New("parent chirp has been deleted")
+)
+
+// chirpResponse is a chirp as returned to clients, along with its likes.
+// LikedByMe is only set when the request was made by an authenticated user.
+// Original is set for rechirps and quote-chirps.
+// Hidden is only ever true for the author, as no one else can see a hidden chirp.
+type chirpResponse struct {
+	database.Chirp
+	LikeCount int64          `json:"like_count"`
+	LikedByMe *bool          `json:"liked_by_me,omitempty"`
+	Original  *originalChirp `json:"original,omitempty"`
+	Hidden    bool           `json:"hidden,omitempty"`
+}
+
+// originalChirp is the chirp that a rechirp or quote-chirp points to.
+// If it has since been deleted or hidden, only the ID and Unavailable are set.
+type originalChirp struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	Body        string    `json:"body,omitempty"`
+	UserID      uuid.UUID `json:"user_id,omitzero"`
+	Unavailable bool      `json:"unavailable"`
+}
+
+type chirpPage struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// newChirpPage trims a result fetched with pageSize+1 rows down to pageSize,
+// setting the next cursor if the extra row shows there is another page.
+func (api *API) newChirpPage(ctx context.Context, chirps []database.Chirp, pageSize int32, viewerID uuid.NullUUID) (chirpPage, error) {
+	var page chirpPage
+	if len(chirps) > int(pageSize) {
+		chirps = chirps[:pageSize]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	var err error
+	page.Chirps, err = api.toChirpResponses(ctx, chirps, viewerID)
+	if err != nil {
+		return chirpPage{}, err
+	}
+	return page, nil
+}
+
+// toChirpResponses adds like counts and rechirped or quoted originals to chirps,
+// looking each of them up in a single query.
+func (api *API) toChirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID) ([]chirpResponse, error) {
+	responses := make([]chirpResponse, len(chirps))
+	if len(chirps) == 0 {
+		return responses, nil
+	}
+
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	var originalIDs []uuid.UUID
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+		if originalID := originalChirpID(chirp); originalID.Valid {
+			originalIDs = append(originalIDs, originalID.UUID)
+		}
+	}
+
+	stats, err := api.DB.GetChirpLikeStats(ctx, database.GetChirpLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting chirp like stats: %v", err)
+	}
+	statsByChirp := make(map[uuid.UUID]database.GetChirpLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		statsByChirp[stat.ChirpID] = stat
+	}
+
+	originals := make(map[uuid.UUID]database.Chirp, len(originalIDs))
+	if len(originalIDs) > 0 {
+		originalChirps, err := api.DB.GetChirpsByIDs(ctx, originalIDs)
+		if err != nil {
+			return nil, fmt.Errorf("getting original chirps: %v", err)
+		}
+		for _, original := range originalChirps {
+			originals[original.ID] = original
+		}
+	}
+
+	for i, chirp := range chirps {
+		stat := statsByChirp[chirp.ID]
+		responses[i] = chirpResponse{
+			Chirp:     chirp,
+			LikeCount: stat.LikeCount,
+			Hidden:    chirp.HiddenAt.Valid,
+		}
+		if viewerID.Valid {
+			responses[i].LikedByMe = &stat.LikedByMe
+		}
+		if originalID := originalChirpID(chirp); originalID.Valid {
+			responses[i].Original = newOriginalChirp(originalID.UUID, originals, viewerID)
+		}
+	}
+	return responses, nil
+}
+
+// originalChirpID returns the ID of the chirp that a rechirp or quote-chirp points to.
+func originalChirpID(chirp database.Chirp) uuid.NullUUID {
+	if chirp.RepostOfID.Valid {
+		return chirp.RepostOfID
+	}
+	return chirp.QuoteOfID
+}
+
+func newOriginalChirp(id uuid.UUID, originals map[uuid.UUID]database.Chirp, viewerID uuid.NullUUID) *originalChirp {
+	original, ok := originals[id]
+	if !ok || !visibleTo(original, viewerID) {
+		return &originalChirp{ID: id, Unavailable: true}
+	}
+	return &originalChirp{
+		ID:        original.ID,
+		CreatedAt: original.CreatedAt,
+		Body:      original.Body,
+		UserID:    original.UserID,
+	}
+}
+
+// visibleTo reports whether a chirp can be seen by the viewer.
+// Deleted chirps can't be seen by anyone, and chirps hidden by a moderator only by their author.
+func visibleTo(chirp database.Chirp, viewerID uuid.NullUUID) bool {
+	if chirp.DeletedAt.Valid {
+		return false
+	}
+	return !chirp.HiddenAt.Valid || (viewerID.Valid && viewerID.UUID == chirp.UserID)
+}
+
+// viewerID returns the ID of the user making the request, for endpoints where authentication is optional.
+// Requests that auth.Middleware let through without a valid access token are anonymous.
+func (api *API) viewerID(r *http.Request) uuid.NullUUID {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	return uuid.NullUUID{UUID: userID, Valid: ok}
+}
+
 func (api *API) GetChirps(w http.ResponseWriter, r *http.Request) {
 	var chirps []database.Chirp
 	var err error
@@ -21,35 +169,61 @@ func (api *API) GetChirps(w http.ResponseWriter, r *http.Request) {
 	authorID := r.URL.Query().Get("author_id")
 	sortOrder := r.URL.Query().Get("sort")
 
+	var authorUUID uuid.NullUUID
 	if authorID != "" {
-		var authorUUID uuid.UUID
-		authorUUID, err = uuid.Parse(authorID)
+		authorUUID.UUID, err = uuid.Parse(authorID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't parse path value 'author_id' to UUID", err)
 			return
 		}
+		authorUUID.Valid = true
+	}
 
-		chirps, err = api.DB.GetChirpsByUserID(r.Context(), authorUUID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirps from DB", err)
-			return
-		}
-	} else {
-		chirps, err = api.DB.GetChirps(r.Context())
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirps from DB", err)
-			return
-		}
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirps: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirps: invalid 'cursor' query param", err)
+		return
 	}
 
+	viewerID := api.viewerID(r)
+
+	// Fetch one more row than requested, to know whether there is a next page
 	if sortOrder == "desc" {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.After(chirps[j].CreatedAt) })
+		chirps, err = api.DB.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams{
+			ViewerID:        viewerID,
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursor.createdAt(),
+			CursorID:        cursor.id(),
+			PageSize:        pageSize + 1,
+		})
 	} else {
 		// "asc" or empty or anything else
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.Before(chirps[j].CreatedAt) })
+		chirps, err = api.DB.GetChirpsPageAsc(r.Context(), database.GetChirpsPageAscParams{
+			ViewerID:        viewerID,
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursor.createdAt(),
+			CursorID:        cursor.id(),
+			PageSize:        pageSize + 1,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirps from DB", err)
+		return
+	}
+
+	page, err := api.newChirpPage(r.Context(), chirps, pageSize, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirp likes from DB", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, chirps)
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 func (api *API) GetChirpByID(w http.ResponseWriter, r *http.Request) {
@@ -68,12 +242,26 @@ func (api *API) GetChirpByID(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirps from DB", err)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, chirp)
+	viewerID := api.viewerID(r)
+	if !visibleTo(chirp, viewerID) {
+		respondWithError(w, http.StatusNotFound, "GetChirps: no chirps found for the given ID", nil)
+		return
+	}
+
+	responses, err := api.toChirpResponses(r.Context(), []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirps: couldn't get chirp likes from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, responses[0])
 }
 
 func (api *API) CreateChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body          string        `json:"body"`
+		ParentChirpID uuid.NullUUID `json:"parent_chirp_id"`
+		QuoteOfID     uuid.NullUUID `json:"quote_of_id"`
 	}
 
 	var params parameters
@@ -82,29 +270,87 @@ func (api *API) CreateChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "CreateChirp: failed to get bearer token", err)
+	userUUID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "CreateChirp: no authenticated user in request context", nil)
 		return
 	}
 
-	userUUID, err := auth.ValidateJWT(token, api.jwtSecret)
+	verified, err := api.emailVerified(r.Context(), userUUID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "CreateChirp: couldn't validate user JWT", err)
+		respondWithError(w, http.StatusInternalServerError, "CreateChirp: couldn't get user from DB", err)
+		return
+	}
+	if !verified {
+		respondWithError(w, http.StatusForbidden, "CreateChirp: email address must be verified before posting chirps", nil)
 		return
 	}
 
-	cBody, err := validateChirp(params.Body)
+	moderated, err := api.validateChirp(params.Body)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "CreateChirp: couldn't validate chirp", err)
+		respondWithError(w, http.StatusBadRequest, "CreateChirp: couldn't validate chirp", err)
 		return
 	}
 
-	chirp, err := api.DB.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cBody,
-		UserID: userUUID,
+	if params.QuoteOfID.Valid {
+		quoted, err := api.DB.GetChirpByID(r.Context(), params.QuoteOfID.UUID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "CreateChirp: no quoted chirp found for the given ID", err)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "CreateChirp: couldn't get quoted chirp from DB", err)
+			return
+		}
+		if !visibleTo(quoted, uuid.NullUUID{UUID: userUUID, Valid: true}) {
+			respondWithError(w, http.StatusBadRequest, "CreateChirp: can't quote a deleted chirp", nil)
+			return
+		}
+		// Quoting a rechirp quotes the chirp it points to
+		if quoted.RepostOfID.Valid {
+			params.QuoteOfID = quoted.RepostOfID
+		}
+	}
+
+	var chirp database.Chirp
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		if params.ParentChirpID.Valid {
+			// Lock the parent, so it can't be deleted between checking it and adding the reply
+			parent, err := q.GetChirpByIDForUpdate(r.Context(), params.ParentChirpID.UUID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return errParentChirpNotFound
+				}
+				return fmt.Errorf("locking parent chirp: %v", err)
+			}
+			if !visibleTo(parent, uuid.NullUUID{UUID: userUUID, Valid: true}) {
+				return errParentChirpDeleted
+			}
+		}
+
+		chirp, err = q.CreateChirp(r.Context(), database.CreateChirpParams{
+			Body:          moderated.Text,
+			UserID:        userUUID,
+			ParentChirpID: params.ParentChirpID,
+			QuoteOfID:     params.QuoteOfID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := indexChirp(r.Context(), q, chirp.ID, chirp.Body); err != nil {
+			return err
+		}
+		return enqueueFlagged(r.Context(), q, chirp, moderated)
 	})
 	if err != nil {
+		if err == errParentChirpNotFound {
+			respondWithError(w, http.StatusBadRequest, "CreateChirp: no parent chirp found for the given ID", err)
+			return
+		}
+		if err == errParentChirpDeleted {
+			respondWithError(w, http.StatusBadRequest, "CreateChirp: can't reply to a deleted chirp", err)
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "CreateChirp: couldn't create chirp in DB", err)
 		return
 	}
@@ -112,29 +358,20 @@ func (api *API) CreateChirp(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusCreated, chirp)
 }
 
-func validateChirp(body string) (string, error) {
+// validateChirp checks a chirp body's length, and runs it through the moderation pipeline.
+// The returned result holds the body with anything censored, and whether it was flagged for review.
+func (api *API) validateChirp(body string) (moderation.Result, error) {
 	const maxChirpLength = 140
 	if len(body) > maxChirpLength {
-		return "", errors.New("chirp is too long")
+		return moderation.Result{}, errors.New("chirp is too long")
 	}
 
-	cBody := profanityCensor(body)
-
-	return cBody, nil
-}
-
-func profanityCensor(s string) string {
-	profanity := []string{"kerfuffle", "sharbert", "fornax"}
-
-	words := strings.Split(s, " ")
-	for i, word := range words {
-		word = strings.ToLower(word)
-		if slices.Contains(profanity, word) {
-			words[i] = "****"
-		}
+	result := api.moderator.Check(body)
+	if result.Action == moderation.Reject {
+		return result, fmt.Errorf("chirp rejected by moderation: %s", strings.Join(result.Reasons, ", "))
 	}
 
-	return strings.Join(words, " ")
+	return result, nil
 }
 
 func (api *API) DeleteChirp(w http.ResponseWriter, r *http.Request) {
@@ -153,16 +390,14 @@ func (api *API) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "DeleteChirp: couldn't get chirps from DB", err)
 		return
 	}
-
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "DeleteChirp: failed to get access token from request header", err)
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "DeleteChirp: no chirps found for the given ID", nil)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, api.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "DeleteChirp: user JWT not authorised", err)
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "DeleteChirp: no authenticated user in request context", nil)
 		return
 	}
 
@@ -171,9 +406,294 @@ func (api *API) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = api.DB.DeleteChirp(r.Context(), chirp.ID); err != nil {
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		return deleteChirp(r.Context(), q, chirp.ID)
+	})
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "DeleteChirp: failed to delete chirp", err)
 		return
 	}
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
+
+// deleteChirp removes a chirp, or replaces it with a tombstone if other chirps reply to or quote it.
+func deleteChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID) error {
+	// Lock the row, so no reply can be added between checking for replies and deleting
+	if _, err := q.GetChirpByIDForUpdate(ctx, chirpID); err != nil {
+		return fmt.Errorf("locking chirp: %v", err)
+	}
+
+	hasReplies, err := q.ChirpHasReplies(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("checking for replies: %v", err)
+	}
+
+	hasQuotes, err := q.ChirpHasQuotes(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("checking for quotes: %v", err)
+	}
+
+	// Rechirps are removed along with the chirp, by the foreign key cascade
+	if !hasReplies && !hasQuotes {
+		return q.DeleteChirp(ctx, chirpID)
+	}
+
+	// Leave a tombstone in place of the chirp, so its replies keep their place in the thread,
+	// and quotes of it show it as unavailable
+	if err := q.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: chirpID, Valid: true}); err != nil {
+		return fmt.Errorf("deleting rechirps: %v", err)
+	}
+	if err := q.DeleteChirpRevisions(ctx, chirpID); err != nil {
+		return fmt.Errorf("deleting chirp revisions: %v", err)
+	}
+	if err := q.TombstoneChirp(ctx, chirpID); err != nil {
+		return fmt.Errorf("tombstoning chirp: %v", err)
+	}
+	return indexChirp(ctx, q, chirpID, "")
+}
+
+func (api *API) UpdateChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID := r.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "UpdateChirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "UpdateChirp: couldn't decode parameters", err)
+		return
+	}
+
+	chirp, err := api.DB.GetChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "UpdateChirp: no chirps found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "UpdateChirp: couldn't get chirps from DB", err)
+		return
+	}
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "UpdateChirp: no chirps found for the given ID", nil)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "UpdateChirp: no authenticated user in request context", nil)
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "UpdateChirp: user ID does not match chirp user ID", err)
+		return
+	}
+
+	// An edit publishes new text, so it needs a verified email address like posting does
+	verified, err := api.emailVerified(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UpdateChirp: couldn't get user from DB", err)
+		return
+	}
+	if !verified {
+		respondWithError(w, http.StatusForbidden, "UpdateChirp: email address must be verified before editing chirps", nil)
+		return
+	}
+
+	if chirp.RepostOfID.Valid {
+		respondWithError(w, http.StatusBadRequest, "UpdateChirp: rechirps can't be edited", nil)
+		return
+	}
+
+	moderated, err := api.validateChirp(params.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "UpdateChirp: couldn't validate chirp", err)
+		return
+	}
+
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		// Lock the row, so concurrent edits can't both save the same old body
+		current, err := q.GetChirpByIDForUpdate(r.Context(), chirp.ID)
+		if err != nil {
+			return fmt.Errorf("locking chirp: %v", err)
+		}
+
+		if _, err := q.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID: current.ID,
+			Body:    current.Body,
+		}); err != nil {
+			return fmt.Errorf("saving chirp revision: %v", err)
+		}
+
+		chirp, err = q.UpdateChirp(r.Context(), database.UpdateChirpParams{
+			ID:   current.ID,
+			Body: moderated.Text,
+		})
+		if err != nil {
+			return fmt.Errorf("updating chirp: %v", err)
+		}
+		if err := indexChirp(r.Context(), q, chirp.ID, chirp.Body); err != nil {
+			return err
+		}
+		return enqueueFlagged(r.Context(), q, chirp, moderated)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UpdateChirp: couldn't update chirp in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (api *API) GetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID := r.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirpRevisions: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	chirp, err := api.DB.GetChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "GetChirpRevisions: no chirps found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "GetChirpRevisions: couldn't get chirps from DB", err)
+		return
+	}
+	if !visibleTo(chirp, api.viewerID(r)) {
+		respondWithError(w, http.StatusNotFound, "GetChirpRevisions: no chirps found for the given ID", nil)
+		return
+	}
+
+	revisions, err := api.DB.GetChirpRevisions(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirpRevisions: couldn't get chirp revisions from DB", err)
+		return
+	}
+	if revisions == nil {
+		revisions = []database.ChirpRevision{}
+	}
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
+
+// threadChirp is a chirp within a conversation tree, holding its direct replies.
+type threadChirp struct {
+	ID            uuid.UUID      `json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Body          string         `json:"body"`
+	UserID        uuid.UUID      `json:"user_id"`
+	ParentChirpID uuid.NullUUID  `json:"parent_chirp_id"`
+	Deleted       bool           `json:"deleted"`
+	Hidden        bool           `json:"hidden"`
+	Replies       []*threadChirp `json:"replies"`
+}
+
+func (api *API) GetChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID := r.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirpThread: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	rows, err := api.DB.GetChirpThread(r.Context(), chirpUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirpThread: couldn't get chirp thread from DB", err)
+		return
+	}
+
+	root := buildThread(rows, api.viewerID(r))
+	if root == nil {
+		respondWithError(w, http.StatusNotFound, "GetChirpThread: no chirps found for the given ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, root)
+}
+
+// buildThread nests thread rows under their parents, returning the root of the conversation.
+// Rows must be ordered by depth, so that parents come before their replies.
+// Chirps hidden by a moderator keep their place in the thread, but their body is only shown to their author.
+func buildThread(rows []database.GetChirpThreadRow, viewerID uuid.NullUUID) *threadChirp {
+	var root *threadChirp
+	nodes := make(map[uuid.UUID]*threadChirp, len(rows))
+
+	for _, row := range rows {
+		node := &threadChirp{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Body:          row.Body,
+			UserID:        row.UserID,
+			ParentChirpID: row.ParentChirpID,
+			Deleted:       row.DeletedAt.Valid,
+			Hidden:        row.HiddenAt.Valid,
+			Replies:       []*threadChirp{},
+		}
+		if node.Hidden && (!viewerID.Valid || viewerID.UUID != row.UserID) {
+			node.Body = ""
+		}
+		nodes[row.ID] = node
+
+		if row.Depth == 0 {
+			root = node
+			continue
+		}
+		if parent, ok := nodes[row.ParentChirpID.UUID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	return root
+}
+
+// GetTimeline returns the newest chirps from the authenticated user and the users they follow.
+func (api *API) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetTimeline: no authenticated user in request context", nil)
+		return
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetTimeline: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetTimeline: invalid 'cursor' query param", err)
+		return
+	}
+
+	// Fetch one more row than requested, to know whether there is a next page
+	chirps, err := api.DB.GetTimelinePage(r.Context(), database.GetTimelinePageParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.createdAt(),
+		CursorID:        cursor.id(),
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetTimeline: couldn't get timeline chirps from DB", err)
+		return
+	}
+
+	page, err := api.newChirpPage(r.Context(), chirps, pageSize, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetTimeline: couldn't get chirp likes from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
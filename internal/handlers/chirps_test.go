@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestBuildThread(t *testing.T) {
+	rootID, replyID, nestedID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now().UTC()
+
+	rows := []database.GetChirpThreadRow{
+		{ID: rootID, CreatedAt: now, Body: "", DeletedAt: sql.NullTime{Time: now, Valid: true}, Depth: 0},
+		{ID: replyID, CreatedAt: now, Body: "reply", ParentChirpID: uuid.NullUUID{UUID: rootID, Valid: true}, Depth: 1},
+		{ID: nestedID, CreatedAt: now, Body: "nested", ParentChirpID: uuid.NullUUID{UUID: replyID, Valid: true}, Depth: 2},
+	}
+
+	root := buildThread(rows, uuid.NullUUID{})
+	if root == nil || root.ID != rootID {
+		t.Fatalf("buildThread() root = %v, want ID %v", root, rootID)
+	}
+	if !root.Deleted {
+		t.Errorf("buildThread() root.Deleted = false, want true")
+	}
+	if len(root.Replies) != 1 || root.Replies[0].ID != replyID {
+		t.Fatalf("buildThread() root.Replies = %v, want one reply with ID %v", root.Replies, replyID)
+	}
+	reply := root.Replies[0]
+	if len(reply.Replies) != 1 || reply.Replies[0].ID != nestedID {
+		t.Fatalf("buildThread() reply.Replies = %v, want one reply with ID %v", reply.Replies, nestedID)
+	}
+
+	if got := buildThread(nil, uuid.NullUUID{}); got != nil {
+		t.Errorf("buildThread(nil) = %v, want nil", got)
+	}
+}
+
+func TestBuildThreadHidden(t *testing.T) {
+	rootID, authorID := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+
+	rows := []database.GetChirpThreadRow{
+		{ID: rootID, CreatedAt: now, Body: "hidden", UserID: authorID, HiddenAt: sql.NullTime{Time: now, Valid: true}, Depth: 0},
+	}
+
+	tests := []struct {
+		name     string
+		viewerID uuid.NullUUID
+		wantBody string
+	}{
+		{
+			name:     "Anonymous viewer",
+			viewerID: uuid.NullUUID{},
+			wantBody: "",
+		},
+		{
+			name:     "Other user",
+			viewerID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			wantBody: "",
+		},
+		{
+			name:     "Author",
+			viewerID: uuid.NullUUID{UUID: authorID, Valid: true},
+			wantBody: "hidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildThread(rows, tt.viewerID)
+			if !root.Hidden {
+				t.Errorf("buildThread() root.Hidden = false, want true")
+			}
+			if root.Body != tt.wantBody {
+				t.Errorf("buildThread() root.Body = %q, want %q", root.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewOriginalChirp(t *testing.T) {
+	availableID, deletedID, hiddenID, missingID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	authorID := uuid.New()
+	originals := map[uuid.UUID]database.Chirp{
+		availableID: {ID: availableID, Body: "original"},
+		deletedID:   {ID: deletedID, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+		hiddenID:    {ID: hiddenID, Body: "hidden", UserID: authorID, HiddenAt: sql.NullTime{Time: time.Now(), Valid: true}},
+	}
+
+	tests := []struct {
+		name            string
+		id              uuid.UUID
+		viewerID        uuid.NullUUID
+		wantBody        string
+		wantUnavailable bool
+	}{
+		{
+			name:            "Available original",
+			id:              availableID,
+			wantBody:        "original",
+			wantUnavailable: false,
+		},
+		{
+			name:            "Deleted original",
+			id:              deletedID,
+			wantBody:        "",
+			wantUnavailable: true,
+		},
+		{
+			name:            "Hidden original",
+			id:              hiddenID,
+			wantBody:        "",
+			wantUnavailable: true,
+		},
+		{
+			name:            "Hidden original viewed by its author",
+			id:              hiddenID,
+			viewerID:        uuid.NullUUID{UUID: authorID, Valid: true},
+			wantBody:        "hidden",
+			wantUnavailable: false,
+		},
+		{
+			name:            "Missing original",
+			id:              missingID,
+			wantBody:        "",
+			wantUnavailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newOriginalChirp(tt.id, originals, tt.viewerID)
+			if got.ID != tt.id {
+				t.Errorf("newOriginalChirp() ID = %v, want %v", got.ID, tt.id)
+			}
+			if got.Body != tt.wantBody {
+				t.Errorf("newOriginalChirp() Body = %q, want %q", got.Body, tt.wantBody)
+			}
+			if got.Unavailable != tt.wantUnavailable {
+				t.Errorf("newOriginalChirp() Unavailable = %v, want %v", got.Unavailable, tt.wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestUpdateChirp(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+	other := createTestUser(t, api, true)
+
+	tests := []struct {
+		name          string
+		editor        database.User
+		edits         []string
+		wantStatus    int
+		wantBody      string
+		wantRevisions []string
+	}{
+		{
+			name:          "author edits once",
+			editor:        author,
+			edits:         []string{"second"},
+			wantStatus:    http.StatusOK,
+			wantBody:      "second",
+			wantRevisions: []string{"first"},
+		},
+		{
+			name:          "author edits twice",
+			editor:        author,
+			edits:         []string{"second", "third"},
+			wantStatus:    http.StatusOK,
+			wantBody:      "third",
+			wantRevisions: []string{"first", "second"},
+		},
+		{
+			name:          "other user can't edit",
+			editor:        other,
+			edits:         []string{"second"},
+			wantStatus:    http.StatusForbidden,
+			wantBody:      "first",
+			wantRevisions: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirp := createTestChirp(t, api, author)
+
+			for _, body := range tt.edits {
+				r := newTestRequest(t, "PUT", "/api/chirps/"+chirp.ID.String(), map[string]string{"body": body})
+				r.SetPathValue("chirpID", chirp.ID.String())
+				serve(t, api.UpdateChirp, asUser(r, tt.editor), tt.wantStatus)
+			}
+
+			got, err := api.DB.GetChirpByID(context.Background(), chirp.ID)
+			if err != nil {
+				t.Fatalf("GetChirpByID() error = %v", err)
+			}
+			if got.Body != tt.wantBody {
+				t.Errorf("chirp body = %q, want %q", got.Body, tt.wantBody)
+			}
+
+			r := newTestRequest(t, "GET", "/api/chirps/"+chirp.ID.String()+"/revisions", nil)
+			r.SetPathValue("chirpID", chirp.ID.String())
+			revisions := decodeResponse[[]database.ChirpRevision](t, serve(t, api.GetChirpRevisions, r, http.StatusOK))
+			gotBodies := []string{}
+			for _, revision := range revisions {
+				gotBodies = append(gotBodies, revision.Body)
+			}
+			if !slices.Equal(gotBodies, tt.wantRevisions) {
+				t.Errorf("GetChirpRevisions() bodies = %q, want %q", gotBodies, tt.wantRevisions)
+			}
+		})
+	}
+}
+
+func TestCreateChirpReply(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+
+	tests := []struct {
+		name       string
+		parent     func(t *testing.T) uuid.UUID
+		wantStatus int
+	}{
+		{
+			name: "reply to a chirp",
+			parent: func(t *testing.T) uuid.UUID {
+				return createTestChirp(t, api, author).ID
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "reply to a deleted chirp",
+			parent: func(t *testing.T) uuid.UUID {
+				parent := createTestChirp(t, api, author)
+				if err := api.DB.TombstoneChirp(context.Background(), parent.ID); err != nil {
+					t.Fatalf("TombstoneChirp() error = %v", err)
+				}
+				return parent.ID
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "reply to a chirp that doesn't exist",
+			parent:     func(t *testing.T) uuid.UUID { return uuid.New() },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequest(t, "POST", "/api/chirps", map[string]any{
+				"body":            "reply",
+				"parent_chirp_id": tt.parent(t),
+			})
+			serve(t, api.CreateChirp, asUser(r, author), tt.wantStatus)
+		})
+	}
+}
+
+func createTestChirp(t *testing.T, api *API, author database.User) database.Chirp {
+	t.Helper()
+	chirp, err := api.DB.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   "first",
+		UserID: author.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	return chirp
+}
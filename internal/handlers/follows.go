@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (api *API) FollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "FollowUser: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "FollowUser: no authenticated user in request context", nil)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "FollowUser: users can't follow themselves", nil)
+		return
+	}
+
+	if _, err := api.DB.GetUserByID(r.Context(), followeeID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "FollowUser: no user found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FollowUser: couldn't get user from DB", err)
+		return
+	}
+
+	err = api.DB.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "FollowUser: couldn't create follow in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (api *API) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "UnfollowUser: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "UnfollowUser: no authenticated user in request context", nil)
+		return
+	}
+
+	err = api.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UnfollowUser: couldn't delete follow from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (api *API) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetFollowers: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	followers, err := api.DB.GetFollowers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetFollowers: couldn't get followers from DB", err)
+		return
+	}
+	if followers == nil {
+		followers = []database.GetFollowersRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, followers)
+}
+
+func (api *API) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetFollowing: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	following, err := api.DB.GetFollowing(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetFollowing: couldn't get followed users from DB", err)
+		return
+	}
+	if following == nil {
+		following = []database.GetFollowingRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, following)
+}
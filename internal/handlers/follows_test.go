@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestFollowUser(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	tests := []struct {
+		name       string
+		followee   func(t *testing.T, follower database.User) uuid.UUID
+		wantStatus int
+	}{
+		{
+			name:       "another user",
+			followee:   func(t *testing.T, follower database.User) uuid.UUID { return createTestUser(t, api, true).ID },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "themselves",
+			followee:   func(t *testing.T, follower database.User) uuid.UUID { return follower.ID },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "user that doesn't exist",
+			followee:   func(t *testing.T, follower database.User) uuid.UUID { return uuid.New() },
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			follower := createTestUser(t, api, true)
+			followeeID := tt.followee(t, follower)
+			followTestUser(t, api, follower, followeeID, tt.wantStatus)
+
+			var wantFollowing []uuid.UUID
+			if tt.wantStatus == http.StatusNoContent {
+				wantFollowing = []uuid.UUID{followeeID}
+			}
+			if got := followIDs(getFollows[database.GetFollowingRow](t, api, api.GetFollowing, follower.ID)); !slices.Equal(got, wantFollowing) {
+				t.Errorf("GetFollowing() = %v, want %v", got, wantFollowing)
+			}
+		})
+	}
+}
+
+func TestUnfollowUser(t *testing.T) {
+	api, _ := newTestAPI(t)
+	follower := createTestUser(t, api, true)
+	followee := createTestUser(t, api, true)
+
+	followTestUser(t, api, follower, followee.ID, http.StatusNoContent)
+	// Following again is a no-op, rather than a second follow
+	followTestUser(t, api, follower, followee.ID, http.StatusNoContent)
+	followers := getFollows[database.GetFollowersRow](t, api, api.GetFollowers, followee.ID)
+	if len(followers) != 1 || followers[0].ID != follower.ID || followers[0].FollowedAt.IsZero() {
+		t.Fatalf("GetFollowers() = %+v, want %v followed once", followers, follower.ID)
+	}
+
+	r := newTestRequest(t, "DELETE", "/api/users/"+followee.ID.String()+"/follow", nil)
+	r.SetPathValue("userID", followee.ID.String())
+	serve(t, api.UnfollowUser, asUser(r, follower), http.StatusNoContent)
+	if followers := getFollows[database.GetFollowersRow](t, api, api.GetFollowers, followee.ID); len(followers) != 0 {
+		t.Errorf("GetFollowers() after unfollowing = %+v, want none", followers)
+	}
+}
+
+func TestGetTimeline(t *testing.T) {
+	api, _ := newTestAPI(t)
+	reader := createTestUser(t, api, true)
+	followed := createTestUser(t, api, true)
+	stranger := createTestUser(t, api, true)
+	followTestUser(t, api, reader, followed.ID, http.StatusNoContent)
+
+	var want []database.Chirp
+	for range 3 {
+		want = append(want, createTestChirp(t, api, followed))
+		createTestChirp(t, api, stranger)
+	}
+	want = append(want, createTestChirp(t, api, reader))
+	// The timeline is newest first, with ties broken by ID
+	slices.SortFunc(want, func(a, b database.Chirp) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), -slices.Compare(a.ID[:], b.ID[:]))
+	})
+
+	var got []uuid.UUID
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatalf("GetTimeline() didn't stop paging after %d pages", pages)
+		}
+		page := getTimelinePage(t, api, reader, cursor)
+		for _, chirp := range page.Chirps {
+			got = append(got, chirp.ID)
+		}
+		if pages == 0 {
+			// Chirps posted while paging are newer than the cursor, so they don't shift the pages after it
+			createTestChirp(t, api, followed)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	var wantIDs []uuid.UUID
+	for _, chirp := range want {
+		wantIDs = append(wantIDs, chirp.ID)
+	}
+	if !slices.Equal(got, wantIDs) {
+		t.Errorf("GetTimeline() chirps = %v, want %v", got, wantIDs)
+	}
+}
+
+func followTestUser(t *testing.T, api *API, follower database.User, followeeID uuid.UUID, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/users/"+followeeID.String()+"/follow", nil)
+	r.SetPathValue("userID", followeeID.String())
+	serve(t, api.FollowUser, asUser(r, follower), wantStatus)
+}
+
+func getFollows[T any](t *testing.T, api *API, handler http.HandlerFunc, userID uuid.UUID) []T {
+	t.Helper()
+	r := newTestRequest(t, "GET", "/api/users/"+userID.String()+"/follows", nil)
+	r.SetPathValue("userID", userID.String())
+	return decodeResponse[[]T](t, serve(t, handler, r, http.StatusOK))
+}
+
+func followIDs(following []database.GetFollowingRow) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, followee := range following {
+		ids = append(ids, followee.ID)
+	}
+	return ids
+}
+
+func getTimelinePage(t *testing.T, api *API, reader database.User, cursor string) chirpPage {
+	t.Helper()
+	query := url.Values{"limit": {"2"}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	r := newTestRequest(t, "GET", "/api/timeline?"+query.Encode(), nil)
+	return decodeResponse[chirpPage](t, serve(t, api.GetTimeline, asUser(r, reader), http.StatusOK))
+}
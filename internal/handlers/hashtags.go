@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+)
+
+var (
+	// '&' is excluded before the '#' so HTML entities like &#39; aren't read as tags
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]+)`)
+	// Matches @handle or @user@example.com
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@([\p{L}\p{N}_.%+-]+(?:@[\p{L}\p{N}.-]+\.\p{L}{2,})?)`)
+)
+
+// extractHashtags returns the unique, lowercased #tags in a chirp body, without the leading '#'.
+func extractHashtags(body string) []string {
+	return uniqueLowerMatches(hashtagPattern, body)
+}
+
+// extractMentions returns the unique, lowercased @mentions in a chirp body, without the leading '@'.
+func extractMentions(body string) []string {
+	return uniqueLowerMatches(mentionPattern, body)
+}
+
+func uniqueLowerMatches(pattern *regexp.Regexp, body string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+		// Trailing dots are sentence punctuation, not part of the mention
+		s := strings.ToLower(strings.TrimRight(match[1], "."))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		found = append(found, s)
+	}
+	return found
+}
+
+// indexChirp records the hashtags and mentions in a chirp's body, replacing any from a previous version.
+// Users don't have handles, so only mentions of a user's email are linked to them.
+func indexChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := q.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return fmt.Errorf("deleting chirp hashtags: %v", err)
+	}
+	if err := q.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return fmt.Errorf("deleting chirp mentions: %v", err)
+	}
+
+	if tags := extractHashtags(body); len(tags) > 0 {
+		if err := q.CreateChirpHashtags(ctx, database.CreateChirpHashtagsParams{
+			ChirpID: chirpID,
+			Tags:    tags,
+		}); err != nil {
+			return fmt.Errorf("creating chirp hashtags: %v", err)
+		}
+	}
+
+	if mentions := extractMentions(body); len(mentions) > 0 {
+		if err := q.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{
+			ChirpID: chirpID,
+			Emails:  mentions,
+		}); err != nil {
+			return fmt.Errorf("creating chirp mentions: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (api *API) GetChirpsByHashtag(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirpsByHashtag: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetChirpsByHashtag: invalid 'cursor' query param", err)
+		return
+	}
+
+	viewerID := api.viewerID(r)
+
+	// Fetch one more row than requested, to know whether there is a next page
+	chirps, err := api.DB.GetChirpsByHashtagPage(r.Context(), database.GetChirpsByHashtagPageParams{
+		Tag:             tag,
+		ViewerID:        viewerID,
+		CursorCreatedAt: cursor.createdAt(),
+		CursorID:        cursor.id(),
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirpsByHashtag: couldn't get chirps from DB", err)
+		return
+	}
+
+	page, err := api.newChirpPage(r.Context(), chirps, pageSize, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetChirpsByHashtag: couldn't get chirp likes from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (api *API) GetMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetMentions: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetMentions: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetMentions: invalid 'cursor' query param", err)
+		return
+	}
+
+	viewerID := api.viewerID(r)
+
+	// Fetch one more row than requested, to know whether there is a next page
+	chirps, err := api.DB.GetMentionsPage(r.Context(), database.GetMentionsPageParams{
+		UserID:          userID,
+		ViewerID:        viewerID,
+		CursorCreatedAt: cursor.createdAt(),
+		CursorID:        cursor.id(),
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetMentions: couldn't get chirps from DB", err)
+		return
+	}
+
+	page, err := api.newChirpPage(r.Context(), chirps, pageSize, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetMentions: couldn't get chirp likes from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// GetTrendingHashtags returns the most used hashtags within a sliding window, e.g. ?window=6h.
+func (api *API) GetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		var err error
+		window, err = time.ParseDuration(s)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "GetTrendingHashtags: 'window' must be a positive duration of at most 168h", err)
+			return
+		}
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetTrendingHashtags: invalid 'limit' query param", err)
+		return
+	}
+
+	trending, err := api.DB.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since:    time.Now().UTC().Add(-window),
+		PageSize: pageSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetTrendingHashtags: couldn't get trending hashtags from DB", err)
+		return
+	}
+	if trending == nil {
+		trending = []database.GetTrendingHashtagsRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, trending)
+}
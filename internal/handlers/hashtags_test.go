@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "Single tag",
+			body: "Loving #Chirpy today",
+			want: []string{"chirpy"},
+		},
+		{
+			name: "Duplicates and punctuation",
+			body: "#go, #Go! and #go_lang.",
+			want: []string{"go", "go_lang"},
+		},
+		{
+			name: "Not preceded by a space",
+			body: "issue#42 and a&#39;",
+			want: nil,
+		},
+		{
+			name: "No tags",
+			body: "just a chirp",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractHashtags(tt.body); !slices.Equal(got, tt.want) {
+				t.Errorf("extractHashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "Email mention",
+			body: "Hi @Alice@Example.com!",
+			want: []string{"alice@example.com"},
+		},
+		{
+			name: "Handle mention with trailing dot",
+			body: "Thanks @bob.",
+			want: []string{"bob"},
+		},
+		{
+			name: "Email address without mention",
+			body: "Mail me at carol@example.com",
+			want: nil,
+		},
+		{
+			name: "Duplicates",
+			body: "@dave @dave@example.com @dave",
+			want: []string{"dave", "dave@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractMentions(tt.body); !slices.Equal(got, tt.want) {
+				t.Errorf("extractMentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
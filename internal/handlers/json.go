@@ -6,6 +6,11 @@ import (
 	"net/http"
 )
 
+// RespondWithError writes an error in the same format as the handlers, for middleware outside this package.
+func RespondWithError(w http.ResponseWriter, statusCode int, msg string, err error) {
+	respondWithError(w, statusCode, msg, err)
+}
+
 func respondWithError(w http.ResponseWriter, statusCode int, msg string, err error) {
 	if err != nil {
 		log.Println(err)
@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// JWKS serves the public keys that verify Chirpy access tokens, so other services can check them
+// without sharing a secret. Clients may cache them for a few minutes.
+func (api *API) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, api.keyring.JWKS())
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (api *API) LikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "LikeChirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "LikeChirp: no authenticated user in request context", nil)
+		return
+	}
+
+	chirp, err := api.DB.GetChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "LikeChirp: no chirps found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "LikeChirp: couldn't get chirps from DB", err)
+		return
+	}
+	if !visibleTo(chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+		respondWithError(w, http.StatusNotFound, "LikeChirp: no chirps found for the given ID", nil)
+		return
+	}
+
+	err = api.DB.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "LikeChirp: couldn't create like in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (api *API) UnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "UnlikeChirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "UnlikeChirp: no authenticated user in request context", nil)
+		return
+	}
+
+	err = api.DB.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UnlikeChirp: couldn't delete like from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestLikeChirp(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+	liker := createTestUser(t, api, true)
+	other := createTestUser(t, api, true)
+
+	tests := []struct {
+		name string
+		// likes and unlikes are how many times the liker likes the chirp, then unlikes it
+		likes         int
+		unlikes       int
+		wantLikeCount int64
+		// wantLikedByMe is liked_by_me for the liker. It is always false for other users, and missing for anonymous viewers
+		wantLikedByMe bool
+	}{
+		{
+			name:          "liked",
+			likes:         1,
+			wantLikeCount: 1,
+			wantLikedByMe: true,
+		},
+		{
+			name:          "liked twice",
+			likes:         2,
+			wantLikeCount: 1,
+			wantLikedByMe: true,
+		},
+		{
+			name:          "unliked",
+			likes:         1,
+			unlikes:       1,
+			wantLikeCount: 0,
+			wantLikedByMe: false,
+		},
+		{
+			name:          "unliked without being liked",
+			unlikes:       1,
+			wantLikeCount: 0,
+			wantLikedByMe: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirp := createTestChirp(t, api, author)
+			for range tt.likes {
+				likeTestChirp(t, api, "POST", api.LikeChirp, liker, chirp.ID)
+			}
+			for range tt.unlikes {
+				likeTestChirp(t, api, "DELETE", api.UnlikeChirp, liker, chirp.ID)
+			}
+
+			viewers := []struct {
+				name   string
+				viewer *database.User
+				// wantLikedByMe is liked_by_me as likedByMe formats it
+				wantLikedByMe any
+			}{
+				{"anonymous", nil, "missing"},
+				{"liker", &liker, tt.wantLikedByMe},
+				{"other user", &other, false},
+			}
+			for _, v := range viewers {
+				got := getTestChirp(t, api, chirp.ID, v.viewer)
+				if got.LikeCount != tt.wantLikeCount {
+					t.Errorf("like_count for %s viewer = %d, want %d", v.name, got.LikeCount, tt.wantLikeCount)
+				}
+				if likedByMe(got) != v.wantLikedByMe {
+					t.Errorf("liked_by_me for %s viewer = %v, want %v", v.name, likedByMe(got), v.wantLikedByMe)
+				}
+			}
+		})
+	}
+}
+
+func likeTestChirp(t *testing.T, api *API, method string, handler http.HandlerFunc, user database.User, chirpID uuid.UUID) {
+	t.Helper()
+	r := newTestRequest(t, method, "/api/chirps/"+chirpID.String()+"/like", nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	serve(t, handler, asUser(r, user), http.StatusNoContent)
+}
+
+// getTestChirp gets a chirp as the viewer sees it, or as an anonymous viewer does if viewer is nil.
+func getTestChirp(t *testing.T, api *API, chirpID uuid.UUID, viewer *database.User) chirpResponse {
+	t.Helper()
+	return decodeResponse[chirpResponse](t, viewTestChirp(t, api, chirpID, viewer, http.StatusOK))
+}
+
+// likedByMe returns liked_by_me from a chirp, or "missing" if it wasn't in the response.
+func likedByMe(chirp chirpResponse) any {
+	if chirp.LikedByMe == nil {
+		return "missing"
+	}
+	return *chirp.LikedByMe
+}
@@ -3,6 +3,9 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -11,133 +14,321 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	accessTokenLifetime  = time.Hour
+	refreshTokenLifetime = 60 * 24 * time.Hour
+)
+
+// errRefreshTokenReused is returned when a refresh token that has already been exchanged or revoked is presented again.
+var errRefreshTokenReused = errors.New("refresh token has already been used or revoked")
+
+// loggedInUser is the response to a completed login.
+type loggedInUser struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Email        string    `json:"email"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	IsChirpyRed  bool      `json:"is_chirpy_red"`
+}
+
+// twoFactorChallenge is the response to a correct password for a user with two-factor authentication,
+// which LoginTwoFactor exchanges for a completed login.
+type twoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
 func (api *API) LoginUser(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 
-	type LoggedInUser struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
-	}
-
 	var params parameters
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "LoginUser: couldn't decode parameters", err)
 		return
 	}
 
+	ip := clientIP(r)
+	if api.loginThrottled(w, r, "LoginUser", params.Email, ip) {
+		return
+	}
+
 	user, err := api.DB.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		api.loginFailed(w, r, "LoginUser", params.Email, ip, err)
 		return
 	}
 
 	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 
 	if !match || err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		api.loginFailed(w, r, "LoginUser", params.Email, ip, err)
+		return
+	}
+	if err := api.logins.passed(r.Context(), ip); err != nil {
+		log.Printf("LoginUser: %v", err)
+	}
+
+	api.startLogin(w, r, "LoginUser", user)
+}
+
+// startLogin logs in a user who has proven who they are with their first factor. Users with
+// two-factor authentication are given a challenge for LoginTwoFactor instead.
+func (api *API) startLogin(w http.ResponseWriter, r *http.Request, funcName string, user database.User) {
+	twoFactor, err := api.twoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, funcName+": couldn't get two-factor authentication from DB", err)
+		return
+	}
+	if twoFactor {
+		// The first factor alone only earns a challenge. The login isn't complete, so failed attempts aren't cleared yet
+		challengeToken, err := auth.MakeJWT(api.keyring, auth.TokenOptions{
+			UserID:    user.ID,
+			ExpiresIn: loginChallengeLifetime,
+			Audience:  []string{auth.LoginChallengeAudience},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, funcName+": couldn't create challenge JWT", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, twoFactorChallenge{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		})
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.ID, api.jwtSecret, time.Hour)
+	api.completeLogin(w, r, funcName, user)
+}
+
+// completeLogin issues an access token and a refresh token to a user who has proven who they are,
+// starting a new session.
+func (api *API) completeLogin(w http.ResponseWriter, r *http.Request, funcName string, user database.User) {
+	if err := api.logins.succeeded(r.Context(), user.Email); err != nil {
+		log.Printf("%s: %v", funcName, err)
+	}
+
+	// The refresh token records the ID of the access token issued with it, so revoking the session can revoke both
+	accessTokenID := uuid.NewString()
+	accessToken, err := auth.MakeJWT(api.keyring, auth.TokenOptions{
+		UserID:    user.ID,
+		Role:      auth.Role(user.Role),
+		ExpiresIn: accessTokenLifetime,
+		Scopes:    auth.AllScopes,
+		ID:        accessTokenID,
+	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "LoginUser: couldn't create access JWT", err)
+		respondWithError(w, http.StatusInternalServerError, funcName+": couldn't create access JWT", err)
 		return
 	}
 
 	refreshTokenString, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "LoginUser: failed to make refresh token", err)
+		respondWithError(w, http.StatusInternalServerError, funcName+": failed to make refresh token", err)
 		return
 	}
 
-	refreshToken, err := api.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshTokenString,
-		ExpiresAt: time.Now().UTC().UTC().Add(60 * 24 * time.Hour),
+	_, err = api.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: auth.HashRefreshToken(refreshTokenString),
+		ExpiresAt: time.Now().UTC().UTC().Add(refreshTokenLifetime),
 		UserID:    user.ID,
+		// Each login starts a new family, which the tokens it is refreshed into will share
+		FamilyID:      uuid.New(),
+		UserAgent:     r.UserAgent(),
+		IpAddress:     clientIP(r),
+		AccessTokenID: accessTokenID,
 	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "LoginUser: failed to store refresh token in DB", err)
+		respondWithError(w, http.StatusInternalServerError, funcName+": failed to store refresh token in DB", err)
 		return
 	}
 
-	loggedInUser := LoggedInUser{
+	respondWithJSON(w, http.StatusOK, loggedInUser{
 		ID:           user.ID,
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
 		Token:        accessToken,
-		RefreshToken: refreshToken.Token,
+		RefreshToken: refreshTokenString,
 		IsChirpyRed:  user.IsChirpyRed,
+	})
+}
+
+// loginThrottled counts a login attempt before its password or code is checked, so attempts made at the same time
+// can't all be checked before they are throttled. It responds, and returns true, if the account or IP address
+// must wait before trying to log in again.
+func (api *API) loginThrottled(w http.ResponseWriter, r *http.Request, funcName string, email string, ip string) bool {
+	wait, accountBlocked, err := api.logins.reserve(r.Context(), email, ip)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, funcName+": couldn't check login attempts", err)
+		return true
+	}
+	if wait <= 0 {
+		return false
 	}
 
-	respondWithJSON(w, http.StatusOK, loggedInUser)
+	setRetryAfter(w, wait)
+	// A locked account answers like a wrong password, so it doesn't give away that the account exists
+	if accountBlocked {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", nil)
+		return true
+	}
+	respondWithError(w, http.StatusTooManyRequests, funcName+": too many failed logins from this address", nil)
+	return true
 }
 
+// loginFailed responds to a failed login, counted by loginThrottled, with how long to wait before trying again.
+func (api *API) loginFailed(w http.ResponseWriter, r *http.Request, funcName string, email string, ip string, err error) {
+	wait, recordErr := api.logins.failed(r.Context(), email, ip)
+	if recordErr != nil {
+		log.Printf("%s: %v", funcName, recordErr)
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+	}
+	respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+}
+
+// RefreshLogin exchanges a refresh token for a new access token and a new refresh token.
+// The presented refresh token is revoked, so each one can only be used once. If a token that
+// was already exchanged or revoked is presented again, either it or its replacement has been stolen,
+// so every token in its family is revoked and the user has to log in again.
+// Expired tokens are just rejected, as they were never revoked.
 func (api *API) RefreshLogin(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	refreshTokenString, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: failed to get bearer token from request header", err)
+		respondWithError(w, http.StatusUnauthorized, "RefreshLogin: failed to get bearer token from request header", err)
 		return
 	}
 
-	refreshToken, err := api.DB.GetRefreshTokenByToken(r.Context(), refreshTokenString)
+	newRefreshTokenString, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: failed to make refresh token", err)
+		return
+	}
+
+	accessTokenID := uuid.NewString()
+
+	var user database.User
+	var reused bool
+	var familyID uuid.UUID
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		// Lock the token, so two concurrent refreshes can't both exchange it
+		refreshToken, err := q.GetRefreshTokenByTokenForUpdate(r.Context(), auth.HashRefreshToken(refreshTokenString))
+		if err != nil {
+			return err
+		}
+
+		// Rotated tokens are revoked too, along with those revoked by logging out or changing the password
+		if refreshToken.RevokedAt.Valid {
+			log.Printf("security: reuse of revoked refresh token detected for user %v, revoking token family %v", refreshToken.UserID, refreshToken.FamilyID)
+			if err := q.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID); err != nil {
+				return fmt.Errorf("revoking refresh token family: %v", err)
+			}
+			// Commit the revocation, rather than rolling it back with an error
+			reused = true
+			familyID = refreshToken.FamilyID
+			return nil
+		}
+
+		user, err = q.GetUserFromValidRefreshToken(r.Context(), refreshToken.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		newRefreshToken, err := q.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+			TokenHash:     auth.HashRefreshToken(newRefreshTokenString),
+			ExpiresAt:     time.Now().UTC().Add(refreshTokenLifetime),
+			UserID:        user.ID,
+			FamilyID:      refreshToken.FamilyID,
+			UserAgent:     r.UserAgent(),
+			IpAddress:     clientIP(r),
+			AccessTokenID: accessTokenID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating refresh token: %v", err)
+		}
+
+		if err := q.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+			ID:         refreshToken.ID,
+			ReplacedBy: uuid.NullUUID{UUID: newRefreshToken.ID, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("revoking rotated refresh token: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			respondWithError(w, http.StatusUnauthorized, "RefreshLogin: no refresh token found for the given token string", err)
+			respondWithError(w, http.StatusUnauthorized, "RefreshLogin: refresh token is invalid, expired or revoked", err)
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: couldn't get refreshToken from DB", err)
+		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: couldn't rotate refresh token in DB", err)
 		return
 	}
-
-	user, err := api.DB.GetUserFromValidRefreshToken(r.Context(), refreshToken.Token)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: couldn't get user for token from DB", err)
+	if reused {
+		if err := api.revokeSessionAccessTokens(r.Context(), familyID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "RefreshLogin: couldn't revoke access tokens of reused refresh token", err)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "RefreshLogin: refresh token has already been used or revoked", errRefreshTokenReused)
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.ID, api.jwtSecret, 1*time.Hour)
+	accessToken, err := auth.MakeJWT(api.keyring, auth.TokenOptions{
+		UserID:    user.ID,
+		Role:      auth.Role(user.Role),
+		ExpiresIn: accessTokenLifetime,
+		Scopes:    auth.AllScopes,
+		ID:        accessTokenID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "RefreshLogin: couldn't make new JWT for user", err)
 		return
 	}
 
-	newToken := response{accessToken}
-	respondWithJSON(w, http.StatusOK, newToken)
+	respondWithJSON(w, http.StatusOK, response{
+		Token:        accessToken,
+		RefreshToken: newRefreshTokenString,
+	})
 }
 
+// RevokeLogin revokes the presented refresh token, and the access token issued with it,
+// logging out the device that holds them.
+// Other sessions are unaffected. See RevokeAllSessions to log out everywhere.
 func (api *API) RevokeLogin(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: failed to get bearer token from request header", err)
+		respondWithError(w, http.StatusUnauthorized, "RevokeLogin: failed to get bearer token from request header", err)
 		return
 	}
 
-	refreshToken, err := api.DB.GetRefreshTokenByToken(r.Context(), token)
+	tokenHash := auth.HashRefreshToken(token)
+	revoked, err := api.DB.RevokeRefreshTokenByToken(r.Context(), tokenHash)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, http.StatusNotFound, "RevokeLogin: no refresh token found for the given token string", err)
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: couldn't get refreshToken from DB", err)
+		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: failed revoke refresh login token in DB", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "RevokeLogin: no active refresh token found for the given token string", nil)
 		return
 	}
 
-	err = api.DB.RevokeRefreshToken(r.Context(), refreshToken.UserID)
+	refreshToken, err := api.DB.GetRefreshTokenByToken(r.Context(), tokenHash)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: failed revoke refresh login token in DB", err)
+		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: couldn't get refresh token from DB", err)
+		return
+	}
+	if err := api.revokeAccessToken(r.Context(), refreshToken.AccessTokenID, refreshToken.CreatedAt); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeLogin: couldn't revoke access token", err)
 		return
 	}
 
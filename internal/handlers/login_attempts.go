@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+// Kinds of login attempt subject, as stored in the login_attempts table.
+const (
+	loginAttemptAccount = "account"
+	loginAttemptIP      = "ip"
+	// Requests for password reset emails are counted apart from logins, so they can't lock anyone out
+	passwordResetAccount = "password_reset_account"
+	passwordResetIP      = "password_reset_ip"
+)
+
+const (
+	// loginFailuresForgottenAfter is how long after the last failed login the count of failures starts again.
+	loginFailuresForgottenAfter = 24 * time.Hour
+	// loginAttemptsSweepInterval is how often each instance deletes the login attempts that have been forgotten.
+	loginAttemptsSweepInterval = time.Hour
+)
+
+// loginPolicy decides how long to wait after a number of consecutive failed logins.
+// A few attempts are free, then the wait doubles with each failure, up to a temporary lockout.
+type loginPolicy struct {
+	freeAttempts    int
+	baseDelay       time.Duration
+	maxDelay        time.Duration
+	lockoutAfter    int
+	lockoutDuration time.Duration
+}
+
+var (
+	accountLoginPolicy = loginPolicy{
+		freeAttempts:    3,
+		baseDelay:       time.Second,
+		maxDelay:        time.Minute,
+		lockoutAfter:    10,
+		lockoutDuration: 15 * time.Minute,
+	}
+	// Many users can share an IP address, so it is given more room before it is slowed down
+	ipLoginPolicy = loginPolicy{
+		freeAttempts:    20,
+		baseDelay:       time.Second,
+		maxDelay:        time.Minute,
+		lockoutAfter:    100,
+		lockoutDuration: 15 * time.Minute,
+	}
+	// Each password reset request sends an email, so only a few are allowed before they are slowed down
+	passwordResetAccountPolicy = loginPolicy{
+		freeAttempts:    3,
+		baseDelay:       time.Minute,
+		maxDelay:        time.Hour,
+		lockoutAfter:    10,
+		lockoutDuration: time.Hour,
+	}
+	passwordResetIPPolicy = loginPolicy{
+		freeAttempts:    10,
+		baseDelay:       time.Minute,
+		maxDelay:        time.Hour,
+		lockoutAfter:    50,
+		lockoutDuration: time.Hour,
+	}
+)
+
+// loginLimit is a kind of subject attempts are counted against, and the policy for how long it waits after them.
+type loginLimit struct {
+	kind   string
+	policy loginPolicy
+}
+
+// wait returns how long after the last of the given number of failures the next login can be attempted.
+func (p loginPolicy) wait(failures int) time.Duration {
+	if failures >= p.lockoutAfter {
+		return p.lockoutDuration
+	}
+	if failures < p.freeAttempts {
+		return 0
+	}
+
+	shift := failures - p.freeAttempts
+	if shift >= 30 {
+		return p.maxDelay
+	}
+	return min(p.baseDelay<<shift, p.maxDelay)
+}
+
+// loginLimiter throttles logins after failed attempts, for each account and each IP address.
+// It throttles requests for password reset emails in the same way.
+// The login_attempts table is shared by every instance. Each instance also remembers who it has blocked,
+// so repeated attempts while blocked are turned away without a database round trip.
+type loginLimiter struct {
+	db      *database.Queries
+	withTx  func(ctx context.Context, fn func(q *database.Queries) error) error
+	account loginLimit
+	ip      loginLimit
+
+	mu      sync.Mutex
+	blocked map[string]time.Time
+	// swept is when forgotten login attempts were last deleted
+	swept time.Time
+}
+
+func newLoginLimiter(db *database.Queries, withTx func(ctx context.Context, fn func(q *database.Queries) error) error) *loginLimiter {
+	return &loginLimiter{
+		db:      db,
+		withTx:  withTx,
+		account: loginLimit{kind: loginAttemptAccount, policy: accountLoginPolicy},
+		ip:      loginLimit{kind: loginAttemptIP, policy: ipLoginPolicy},
+		blocked: make(map[string]time.Time),
+	}
+}
+
+// newPasswordResetLimiter returns a loginLimiter for requests for password reset emails, which are all counted.
+func newPasswordResetLimiter(db *database.Queries, withTx func(ctx context.Context, fn func(q *database.Queries) error) error) *loginLimiter {
+	return &loginLimiter{
+		db:      db,
+		withTx:  withTx,
+		account: loginLimit{kind: passwordResetAccount, policy: passwordResetAccountPolicy},
+		ip:      loginLimit{kind: passwordResetIP, policy: passwordResetIPPolicy},
+		blocked: make(map[string]time.Time),
+	}
+}
+
+// reserve counts a login attempt against the account and IP address before it is checked, so attempts made
+// at the same time are throttled by each other, rather than all being checked before any is counted.
+// If the account or IP address must wait first, nothing is counted, and reserve returns how long the wait is.
+// accountBlocked says whether it is the account, rather than the IP address, that must wait.
+// An attempt that turns out to be correct is forgotten again by passed and succeeded.
+func (l *loginLimiter) reserve(ctx context.Context, email string, ip string) (wait time.Duration, accountBlocked bool, err error) {
+	now := time.Now()
+	account := normaliseLoginEmail(email)
+	if wait := l.blockedFor(l.account.kind, account, now); wait > 0 {
+		return wait, true, nil
+	}
+	if wait := l.blockedFor(l.ip.kind, ip, now); wait > 0 {
+		return wait, false, nil
+	}
+
+	if err := l.sweep(ctx, now); err != nil {
+		return 0, false, err
+	}
+
+	err = l.withTx(ctx, func(q *database.Queries) error {
+		// Lock the account before the IP address, in the same order as every other attempt, so they can't deadlock
+		accountAttempts, err := lockLoginAttempts(ctx, q, l.account.kind, account)
+		if err != nil {
+			return err
+		}
+		ipAttempts, err := lockLoginAttempts(ctx, q, l.ip.kind, ip)
+		if err != nil {
+			return err
+		}
+
+		if wait = l.block(l.account.kind, account, accountAttempts.LastFailedAt.Add(l.account.policy.wait(int(accountAttempts.Failures))), now); wait > 0 {
+			accountBlocked = true
+			return nil
+		}
+		if wait = l.block(l.ip.kind, ip, ipAttempts.LastFailedAt.Add(l.ip.policy.wait(int(ipAttempts.Failures))), now); wait > 0 {
+			return nil
+		}
+
+		for _, attempt := range []database.RecordLoginFailureParams{
+			{Kind: l.account.kind, Subject: account},
+			{Kind: l.ip.kind, Subject: ip},
+		} {
+			attempt.LastFailedAt = now.UTC().Add(-loginFailuresForgottenAfter)
+			if _, err := q.RecordLoginFailure(ctx, attempt); err != nil {
+				return fmt.Errorf("recording login attempt: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return wait, accountBlocked, nil
+}
+
+// lockLoginAttempts locks the login attempts of a subject until the end of the transaction,
+// creating them first if it has none, so there is always a row to lock.
+func lockLoginAttempts(ctx context.Context, q *database.Queries, kind string, subject string) (database.LoginAttempt, error) {
+	// A sweep can delete forgotten attempts between creating and locking them, so they are created again if so
+	for range 2 {
+		if err := q.CreateLoginAttempts(ctx, database.CreateLoginAttemptsParams{Kind: kind, Subject: subject}); err != nil {
+			return database.LoginAttempt{}, fmt.Errorf("creating login attempts: %v", err)
+		}
+		attempts, err := q.GetLoginAttemptsForUpdate(ctx, database.GetLoginAttemptsForUpdateParams{Kind: kind, Subject: subject})
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return database.LoginAttempt{}, fmt.Errorf("locking login attempts: %v", err)
+		}
+		return attempts, nil
+	}
+	return database.LoginAttempt{}, fmt.Errorf("locking login attempts: %v", sql.ErrNoRows)
+}
+
+// sweep deletes the login attempts that have been forgotten, at most once per loginAttemptsSweepInterval.
+// Every email address and IP address that is tried gets a row, whether or not it has an account,
+// so without sweeping the table would grow with every address anyone makes up.
+func (l *loginLimiter) sweep(ctx context.Context, now time.Time) error {
+	l.mu.Lock()
+	due := now.Sub(l.swept) >= loginAttemptsSweepInterval
+	if due {
+		l.swept = now
+	}
+	l.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	if err := l.db.DeleteStaleLoginAttempts(ctx, now.UTC().Add(-loginFailuresForgottenAfter)); err != nil {
+		return fmt.Errorf("deleting stale login attempts: %v", err)
+	}
+	return nil
+}
+
+// failed returns how long the account or IP address must now wait before trying again,
+// after an attempt counted by reserve turned out to be wrong.
+func (l *loginLimiter) failed(ctx context.Context, email string, ip string) (time.Duration, error) {
+	now := time.Now()
+	accountWait, err := l.load(ctx, l.account, normaliseLoginEmail(email), now)
+	if err != nil {
+		return 0, err
+	}
+	ipWait, err := l.load(ctx, l.ip, ip, now)
+	if err != nil {
+		return 0, err
+	}
+	return max(accountWait, ipWait), nil
+}
+
+// passed forgets the attempt reserved for the IP address, once the password or code turns out to be correct,
+// so users logging in from a shared address don't slow each other down.
+func (l *loginLimiter) passed(ctx context.Context, ip string) error {
+	if err := l.db.ReleaseLoginAttempt(ctx, database.ReleaseLoginAttemptParams{
+		Kind:    l.ip.kind,
+		Subject: ip,
+	}); err != nil {
+		return fmt.Errorf("releasing login attempt: %v", err)
+	}
+	return nil
+}
+
+// succeeded forgets the failed logins for an account. Failures from the IP address are kept,
+// so logging in to one account doesn't let an attacker keep guessing the passwords of others.
+func (l *loginLimiter) succeeded(ctx context.Context, email string) error {
+	subject := normaliseLoginEmail(email)
+	l.mu.Lock()
+	delete(l.blocked, l.account.kind+":"+subject)
+	l.mu.Unlock()
+
+	if err := l.db.ClearLoginAttempts(ctx, database.ClearLoginAttemptsParams{
+		Kind:    l.account.kind,
+		Subject: subject,
+	}); err != nil {
+		return fmt.Errorf("clearing login attempts: %v", err)
+	}
+	return nil
+}
+
+func (l *loginLimiter) load(ctx context.Context, limit loginLimit, subject string, now time.Time) (time.Duration, error) {
+	attempts, err := l.db.GetLoginAttempts(ctx, database.GetLoginAttemptsParams{Kind: limit.kind, Subject: subject})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("getting login attempts: %v", err)
+	}
+	return l.block(limit.kind, subject, attempts.LastFailedAt.Add(limit.policy.wait(int(attempts.Failures))), now), nil
+}
+
+// block remembers that a subject can't log in until the given time, returning how long that is from now.
+func (l *loginLimiter) block(kind string, subject string, until time.Time, now time.Time) time.Duration {
+	wait := until.Sub(now)
+	if wait <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.blocked[kind+":"+subject] = until
+	// Blocks that have run out are only swept now and then, rather than on every login
+	if len(l.blocked) > 10_000 {
+		for key, until := range l.blocked {
+			if !until.After(now) {
+				delete(l.blocked, key)
+			}
+		}
+	}
+	return wait
+}
+
+func (l *loginLimiter) blockedFor(kind string, subject string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until, ok := l.blocked[kind+":"+subject]
+	if !ok {
+		return 0
+	}
+	if !until.After(now) {
+		delete(l.blocked, kind+":"+subject)
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// normaliseLoginEmail folds the case of an email address, so changing it doesn't get around the limit.
+func normaliseLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// setRetryAfter sets the Retry-After header to wait, rounded up to whole seconds.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestLoginPolicyWait(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{9, time.Minute},
+		{10, 15 * time.Minute},
+		{1000, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := accountLoginPolicy.wait(tt.failures); got != tt.want {
+			t.Errorf("wait(%v) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	if got := (loginPolicy{freeAttempts: 0, baseDelay: time.Second, maxDelay: time.Hour, lockoutAfter: 1000}).wait(100); got != time.Hour {
+		t.Errorf("wait() with a large shift = %v, want the max delay", got)
+	}
+}
+
+func TestLoginLimiterFastPath(t *testing.T) {
+	// Without a database, reserve only succeeds if it answers from memory
+	l := newLoginLimiter(nil, nil)
+	now := time.Now()
+	l.block(loginAttemptAccount, "user@example.com", now.Add(time.Minute), now)
+	l.block(loginAttemptIP, "203.0.113.7", now.Add(30*time.Second), now)
+
+	tests := []struct {
+		name        string
+		email       string
+		ip          string
+		wantAccount bool
+	}{
+		{
+			name:        "Blocked account",
+			email:       "User@Example.com",
+			ip:          "198.51.100.1",
+			wantAccount: true,
+		},
+		{
+			name:        "Blocked IP",
+			email:       "other@example.com",
+			ip:          "203.0.113.7",
+			wantAccount: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, accountBlocked, err := l.reserve(context.Background(), tt.email, tt.ip)
+			if err != nil {
+				t.Fatalf("reserve() error = %v", err)
+			}
+			if wait <= 0 {
+				t.Errorf("reserve() wait = %v, want > 0", wait)
+			}
+			if accountBlocked != tt.wantAccount {
+				t.Errorf("reserve() accountBlocked = %v, want %v", accountBlocked, tt.wantAccount)
+			}
+		})
+	}
+}
+
+func TestSetRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	setRetryAfter(rec, 1500*time.Millisecond)
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+}
+
+func TestLoginUserConcurrentAttempts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	user := createTestUser(t, api, true)
+	ip := testIP()
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	for range attempts {
+		r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": "wrong password"})
+		r.RemoteAddr = ip + ":1234"
+		wg.Go(func() {
+			w := httptest.NewRecorder()
+			api.LoginUser(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("LoginUser() status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Every attempt is counted before its password is checked, so only the free attempts are checked at all
+	got, err := api.DB.GetLoginAttempts(context.Background(), database.GetLoginAttemptsParams{
+		Kind:    loginAttemptAccount,
+		Subject: normaliseLoginEmail(user.Email),
+	})
+	if err != nil {
+		t.Fatalf("GetLoginAttempts() error = %v", err)
+	}
+	if int(got.Failures) != accountLoginPolicy.freeAttempts {
+		t.Errorf("checked %d of %d concurrent attempts, want %d", got.Failures, attempts, accountLoginPolicy.freeAttempts)
+	}
+}
+
+func TestLoginUserForgetsCorrectAttempts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	user := createTestUser(t, api, true)
+	ip := testIP()
+
+	for _, password := range []string{"wrong password", testPassword} {
+		r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": password})
+		r.RemoteAddr = ip + ":1234"
+		api.LoginUser(httptest.NewRecorder(), r)
+	}
+
+	tests := []struct {
+		kind         string
+		subject      string
+		wantFailures int32
+	}{
+		{loginAttemptAccount, normaliseLoginEmail(user.Email), 0},
+		{loginAttemptIP, ip, 1},
+	}
+
+	for _, tt := range tests {
+		got, err := api.DB.GetLoginAttempts(context.Background(), database.GetLoginAttemptsParams{Kind: tt.kind, Subject: tt.subject})
+		if err != nil && err != sql.ErrNoRows {
+			t.Fatalf("GetLoginAttempts() error = %v", err)
+		}
+		if got.Failures != tt.wantFailures {
+			t.Errorf("%s login failures = %d, want %d", tt.kind, got.Failures, tt.wantFailures)
+		}
+	}
+}
+
+func TestLoginLimiterSweepsStaleAttempts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	stale, recent := testIP(), testIP()
+	for _, ip := range []string{stale, recent} {
+		if _, err := api.DB.RecordLoginFailure(context.Background(), database.RecordLoginFailureParams{
+			Kind:         loginAttemptIP,
+			Subject:      ip,
+			LastFailedAt: time.Now().UTC().Add(-loginFailuresForgottenAfter),
+		}); err != nil {
+			t.Fatalf("RecordLoginFailure() error = %v", err)
+		}
+	}
+	if _, err := api.db.ExecContext(context.Background(),
+		"UPDATE login_attempts SET last_failed_at = $1 WHERE kind = $2 AND subject = $3",
+		time.Now().UTC().Add(-loginFailuresForgottenAfter-time.Minute), loginAttemptIP, stale,
+	); err != nil {
+		t.Fatalf("ageing login attempts: %v", err)
+	}
+
+	// A new limiter has never swept, so its first attempt does
+	l := newLoginLimiter(api.DB, api.withTx)
+	if _, _, err := l.reserve(context.Background(), uuid.NewString()+"@example.com", testIP()); err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		wantErr error
+	}{
+		{"forgotten attempts", stale, sql.ErrNoRows},
+		{"recent attempts", recent, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.DB.GetLoginAttempts(context.Background(), database.GetLoginAttemptsParams{Kind: loginAttemptIP, Subject: tt.subject})
+			if err != tt.wantErr {
+				t.Errorf("GetLoginAttempts() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testIP returns a random address in 10.0.0.0/8, so tests sharing a database don't throttle each other.
+func testIP() string {
+	b := uuid.New()
+	return fmt.Sprintf("10.%d.%d.%d", b[0], b[1], b[2])
+}
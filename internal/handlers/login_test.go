@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+type refreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func TestRefreshLoginRotates(t *testing.T) {
+	api, _ := newTestAPI(t)
+	loggedIn := loginTestUser(t, api, createTestUser(t, api, true))
+
+	first := decodeResponse[refreshResponse](t, refreshLogin(t, api, loggedIn.RefreshToken, http.StatusOK))
+	if first.RefreshToken == loggedIn.RefreshToken {
+		t.Fatalf("RefreshLogin() returned the same refresh token")
+	}
+	second := decodeResponse[refreshResponse](t, refreshLogin(t, api, first.RefreshToken, http.StatusOK))
+
+	old, err := api.DB.GetRefreshTokenByToken(context.Background(), auth.HashRefreshToken(first.RefreshToken))
+	if err != nil {
+		t.Fatalf("GetRefreshTokenByToken() error = %v", err)
+	}
+	current, err := api.DB.GetRefreshTokenByToken(context.Background(), auth.HashRefreshToken(second.RefreshToken))
+	if err != nil {
+		t.Fatalf("GetRefreshTokenByToken() error = %v", err)
+	}
+	if !old.RevokedAt.Valid || old.ReplacedBy.UUID != current.ID {
+		t.Errorf("rotated token = %+v, want revoked and replaced by %v", old, current.ID)
+	}
+	if current.FamilyID != old.FamilyID || current.RevokedAt.Valid {
+		t.Errorf("new token = %+v, want active in family %v", current, old.FamilyID)
+	}
+}
+
+func TestRefreshLoginReuseRevokesFamily(t *testing.T) {
+	api, _ := newTestAPI(t)
+	authn := auth.NewMiddleware(api.keyring, api.denylist)
+	protected := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		// revoke revokes a token from the login, returning it along with a token from the same family that is still active
+		revoke func(t *testing.T, loggedIn loggedInUser) (revoked string, active refreshResponse)
+	}{
+		{
+			name: "rotated token",
+			revoke: func(t *testing.T, loggedIn loggedInUser) (string, refreshResponse) {
+				return loggedIn.RefreshToken, decodeResponse[refreshResponse](t, refreshLogin(t, api, loggedIn.RefreshToken, http.StatusOK))
+			},
+		},
+		{
+			name: "logged out token",
+			revoke: func(t *testing.T, loggedIn loggedInUser) (string, refreshResponse) {
+				active := decodeResponse[refreshResponse](t, refreshLogin(t, api, loggedIn.RefreshToken, http.StatusOK))
+				// Another device can't share a session, so add a token to the family directly
+				sibling, err := auth.MakeRefreshToken()
+				if err != nil {
+					t.Fatalf("MakeRefreshToken() error = %v", err)
+				}
+				token, err := api.DB.GetRefreshTokenByToken(context.Background(), auth.HashRefreshToken(active.RefreshToken))
+				if err != nil {
+					t.Fatalf("GetRefreshTokenByToken() error = %v", err)
+				}
+				if _, err := api.DB.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
+					TokenHash: auth.HashRefreshToken(sibling),
+					ExpiresAt: token.ExpiresAt,
+					UserID:    token.UserID,
+					FamilyID:  token.FamilyID,
+				}); err != nil {
+					t.Fatalf("CreateRefreshToken() error = %v", err)
+				}
+
+				r := newTestRequest(t, "POST", "/api/revoke", nil)
+				r.Header.Set("Authorization", "Bearer "+sibling)
+				serve(t, api.RevokeLogin, r, http.StatusNoContent)
+				return sibling, active
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loggedIn := loginTestUser(t, api, createTestUser(t, api, true))
+			revoked, active := tt.revoke(t, loggedIn)
+
+			refreshLogin(t, api, revoked, http.StatusUnauthorized)
+			refreshLogin(t, api, active.RefreshToken, http.StatusUnauthorized)
+			if got := useAccessToken(protected, active.Token); got != http.StatusUnauthorized {
+				t.Errorf("access token of revoked family status = %d, want %d", got, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestRefreshLoginConcurrent(t *testing.T) {
+	api, _ := newTestAPI(t)
+	loggedIn := loginTestUser(t, api, createTestUser(t, api, true))
+
+	const refreshes = 5
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, refreshes)
+	for i := range refreshes {
+		wg.Go(func() {
+			r := httptest.NewRequest("POST", "/api/refresh", nil)
+			r.Header.Set("Authorization", "Bearer "+loggedIn.RefreshToken)
+			responses[i] = httptest.NewRecorder()
+			api.RefreshLogin(responses[i], r)
+		})
+	}
+	wg.Wait()
+
+	// The token is locked, so one refresh exchanges it, and the rest are treated as reuse
+	var exchanged []refreshResponse
+	for _, w := range responses {
+		switch w.Code {
+		case http.StatusOK:
+			exchanged = append(exchanged, decodeResponse[refreshResponse](t, w))
+		case http.StatusUnauthorized:
+		default:
+			t.Errorf("RefreshLogin() status = %d, want %d or %d", w.Code, http.StatusOK, http.StatusUnauthorized)
+		}
+	}
+	if len(exchanged) != 1 {
+		t.Fatalf("RefreshLogin() exchanged the token %d times, want once", len(exchanged))
+	}
+	// The reuse revoked the family, including the token the exchange returned
+	refreshLogin(t, api, exchanged[0].RefreshToken, http.StatusUnauthorized)
+}
+
+// loginTestUser logs a user made by createTestUser in with their password.
+func loginTestUser(t *testing.T, api *API, user database.User) loggedInUser {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": testPassword})
+	return decodeResponse[loggedInUser](t, serve(t, api.LoginUser, r, http.StatusOK))
+}
+
+func refreshLogin(t *testing.T, api *API, refreshToken string, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/refresh", nil)
+	r.Header.Set("Authorization", "Bearer "+refreshToken)
+	return serve(t, api.RefreshLogin, r, wantStatus)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	moderationPending  = "pending"
+	moderationApproved = "approved"
+	moderationHidden   = "hidden"
+	moderationDeleted  = "deleted"
+)
+
+// moderationDecisions maps the action in a moderator's request to the status it leaves queue items in.
+var moderationDecisions = map[string]string{
+	"approve": moderationApproved,
+	"hide":    moderationHidden,
+	"delete":  moderationDeleted,
+}
+
+// errAlreadyDecided is returned when a moderator acts on an item that has already been decided.
+var errAlreadyDecided = errors.New("moderation item has already been decided")
+
+// moderationItem is an entry in the moderation queue as returned to moderators.
+type moderationItem struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ChirpID        *uuid.UUID `json:"chirp_id"`
+	ChirpBody      string     `json:"chirp_body"`
+	Source         string     `json:"source"`
+	ReporterID     *uuid.UUID `json:"reporter_id,omitempty"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	DecidedBy      *uuid.UUID `json:"decided_by,omitempty"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	DecisionReason *string    `json:"decision_reason,omitempty"`
+}
+
+type moderationPage struct {
+	Items      []moderationItem `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func newModerationItem(item database.ModerationQueue) moderationItem {
+	response := moderationItem{
+		ID:        item.ID,
+		CreatedAt: item.CreatedAt,
+		ChirpBody: item.ChirpBody,
+		Source:    item.Source,
+		Reason:    item.Reason,
+		Status:    item.Status,
+	}
+	if item.ChirpID.Valid {
+		response.ChirpID = &item.ChirpID.UUID
+	}
+	if item.ReporterID.Valid {
+		response.ReporterID = &item.ReporterID.UUID
+	}
+	if item.DecidedBy.Valid {
+		response.DecidedBy = &item.DecidedBy.UUID
+	}
+	if item.DecidedAt.Valid {
+		response.DecidedAt = &item.DecidedAt.Time
+	}
+	if item.DecisionReason.Valid {
+		response.DecisionReason = &item.DecisionReason.String
+	}
+	return response
+}
+
+// enqueueFlagged adds a chirp to the moderation queue if the pipeline flagged it for review.
+// The chirp stays visible until a moderator decides to hide or delete it.
+func enqueueFlagged(ctx context.Context, q *database.Queries, chirp database.Chirp, result moderation.Result) error {
+	if result.Action != moderation.Flag {
+		return nil
+	}
+	if err := q.CreateModerationItem(ctx, database.CreateModerationItemParams{
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ChirpBody: chirp.Body,
+		Source:    "filter",
+		Reason:    strings.Join(result.Reasons, ", "),
+	}); err != nil {
+		return fmt.Errorf("queueing flagged chirp: %v", err)
+	}
+	return nil
+}
+
+// ReportChirp lets a user report a chirp to the moderators.
+// Reporting a chirp again while the first report is pending has no effect.
+func (api *API) ReportChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ReportChirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "ReportChirp: couldn't decode parameters", err)
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" {
+		respondWithError(w, http.StatusBadRequest, "ReportChirp: a reason is required", nil)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "ReportChirp: no authenticated user in request context", nil)
+		return
+	}
+
+	chirp, err := api.DB.GetChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "ReportChirp: no chirps found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "ReportChirp: couldn't get chirps from DB", err)
+		return
+	}
+	if !visibleTo(chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+		respondWithError(w, http.StatusNotFound, "ReportChirp: no chirps found for the given ID", nil)
+		return
+	}
+
+	err = api.DB.CreateModerationItem(r.Context(), database.CreateModerationItemParams{
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ChirpBody:  chirp.Body,
+		Source:     "report",
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:     params.Reason,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "ReportChirp: couldn't create report in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// GetModerationQueue lists moderation items with the given ?status, oldest first. It defaults to pending items.
+// Only moderators can reach it.
+func (api *API) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = moderationPending
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetModerationQueue: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetModerationQueue: invalid 'cursor' query param", err)
+		return
+	}
+
+	// Fetch one more row than requested, to know whether there is a next page
+	items, err := api.DB.GetModerationItemsPage(r.Context(), database.GetModerationItemsPageParams{
+		Status:          status,
+		CursorCreatedAt: cursor.createdAt(),
+		CursorID:        cursor.id(),
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetModerationQueue: couldn't get moderation items from DB", err)
+		return
+	}
+
+	var page moderationPage
+	if len(items) > int(pageSize) {
+		items = items[:pageSize]
+		last := items[len(items)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	page.Items = make([]moderationItem, len(items))
+	for i, item := range items {
+		page.Items[i] = newModerationItem(item)
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// DecideModerationItem approves, hides or deletes the chirp behind a moderation item.
+// Every other pending item for the same chirp is decided along with it. Only moderators can reach it.
+func (api *API) DecideModerationItem(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	moderatorID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "DecideModerationItem: no authenticated user in request context", nil)
+		return
+	}
+
+	itemID, err := uuid.Parse(r.PathValue("itemID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "DecideModerationItem: couldn't parse path value 'itemID' to UUID", err)
+		return
+	}
+
+	status, ok := moderationDecisions[r.PathValue("action")]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "DecideModerationItem: action must be one of approve, hide or delete", nil)
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "DecideModerationItem: couldn't decode parameters", err)
+		return
+	}
+
+	var item database.ModerationQueue
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		// Lock the item, so two moderators can't both decide it
+		item, err = q.GetModerationItemForUpdate(r.Context(), itemID)
+		if err != nil {
+			return err
+		}
+		if item.Status != moderationPending {
+			return errAlreadyDecided
+		}
+
+		if err := q.DecideModerationItems(r.Context(), database.DecideModerationItemsParams{
+			Status:         status,
+			DecidedBy:      uuid.NullUUID{UUID: moderatorID, Valid: true},
+			DecisionReason: sql.NullString{String: params.Reason, Valid: params.Reason != ""},
+			ID:             item.ID,
+			ChirpID:        item.ChirpID,
+		}); err != nil {
+			return fmt.Errorf("deciding moderation items: %v", err)
+		}
+
+		// The chirp may already have been deleted by its author
+		if !item.ChirpID.Valid {
+			return nil
+		}
+		switch status {
+		case moderationApproved:
+			return q.UnhideChirp(r.Context(), item.ChirpID.UUID)
+		case moderationHidden:
+			return q.HideChirp(r.Context(), item.ChirpID.UUID)
+		default:
+			return deleteChirp(r.Context(), q, item.ChirpID.UUID)
+		}
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "DecideModerationItem: no moderation items found for the given ID", err)
+			return
+		}
+		if err == errAlreadyDecided {
+			respondWithError(w, http.StatusConflict, "DecideModerationItem: moderation item has already been decided", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "DecideModerationItem: couldn't decide moderation item", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestReportChirp(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+	reporter := createTestUser(t, api, true)
+
+	tests := []struct {
+		name       string
+		chirpID    func(t *testing.T) uuid.UUID
+		reason     string
+		wantStatus int
+	}{
+		{
+			name:       "chirp with a reason",
+			chirpID:    func(t *testing.T) uuid.UUID { return createTestChirp(t, api, author).ID },
+			reason:     "spam",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "no reason",
+			chirpID:    func(t *testing.T) uuid.UUID { return createTestChirp(t, api, author).ID },
+			reason:     "  ",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "chirp that doesn't exist",
+			chirpID:    func(t *testing.T) uuid.UUID { return uuid.New() },
+			reason:     "spam",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirpID := tt.chirpID(t)
+			reportTestChirp(t, api, reporter, chirpID, tt.reason, tt.wantStatus)
+			if tt.wantStatus != http.StatusNoContent {
+				return
+			}
+
+			item := findModerationItem(t, api, chirpID, moderationPending)
+			if item.Source != "report" || item.Reason != tt.reason || item.ReporterID == nil || *item.ReporterID != reporter.ID {
+				t.Errorf("moderation item = %+v, want a report from %v with reason %q", item, reporter.ID, tt.reason)
+			}
+		})
+	}
+}
+
+func TestDecideModerationItem(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+	reporter := createTestUser(t, api, true)
+	moderator := createTestUser(t, api, true)
+	const reason = "decided by a test"
+
+	tests := []struct {
+		action     string
+		wantStatus string
+		// wantAuthorStatus and wantOtherStatus are the statuses of the author and another user getting the chirp
+		wantAuthorStatus int
+		wantOtherStatus  int
+	}{
+		{
+			action:           "approve",
+			wantStatus:       moderationApproved,
+			wantAuthorStatus: http.StatusOK,
+			wantOtherStatus:  http.StatusOK,
+		},
+		{
+			action:           "hide",
+			wantStatus:       moderationHidden,
+			wantAuthorStatus: http.StatusOK,
+			wantOtherStatus:  http.StatusNotFound,
+		},
+		{
+			action:           "delete",
+			wantStatus:       moderationDeleted,
+			wantAuthorStatus: http.StatusNotFound,
+			wantOtherStatus:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			chirp := createTestChirp(t, api, author)
+			reportTestChirp(t, api, reporter, chirp.ID, "spam", http.StatusNoContent)
+			item := findModerationItem(t, api, chirp.ID, moderationPending)
+
+			decideModerationItem(t, api, moderator, item.ID, tt.action, reason, http.StatusNoContent)
+
+			decided, err := api.DB.GetModerationItem(context.Background(), item.ID)
+			if err != nil {
+				t.Fatalf("GetModerationItem() error = %v", err)
+			}
+			if decided.Status != tt.wantStatus || decided.DecidedBy.UUID != moderator.ID || !decided.DecidedAt.Valid || decided.DecisionReason.String != reason {
+				t.Errorf("decided item = %+v, want %s by %v with reason %q", decided, tt.wantStatus, moderator.ID, reason)
+			}
+
+			w := viewTestChirp(t, api, chirp.ID, &author, tt.wantAuthorStatus)
+			if tt.wantAuthorStatus == http.StatusOK {
+				if got := decodeResponse[chirpResponse](t, w); got.Hidden != (tt.action == "hide") {
+					t.Errorf("chirp hidden for its author = %v, want %v", got.Hidden, tt.action == "hide")
+				}
+			}
+			viewTestChirp(t, api, chirp.ID, &reporter, tt.wantOtherStatus)
+			viewTestChirp(t, api, chirp.ID, nil, tt.wantOtherStatus)
+
+			// The decision can't be changed by deciding the item again
+			decideModerationItem(t, api, moderator, item.ID, "approve", "", http.StatusConflict)
+		})
+	}
+}
+
+func reportTestChirp(t *testing.T, api *API, reporter database.User, chirpID uuid.UUID, reason string, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/chirps/"+chirpID.String()+"/report", map[string]string{"reason": reason})
+	r.SetPathValue("chirpID", chirpID.String())
+	serve(t, api.ReportChirp, asUser(r, reporter), wantStatus)
+}
+
+func decideModerationItem(t *testing.T, api *API, moderator database.User, itemID uuid.UUID, action string, reason string, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/admin/moderation/"+itemID.String()+"/"+action, map[string]string{"reason": reason})
+	r.SetPathValue("itemID", itemID.String())
+	r.SetPathValue("action", action)
+	serve(t, api.DecideModerationItem, asUser(r, moderator), wantStatus)
+}
+
+// findModerationItem pages through the moderation queue for the item with the given status about a chirp.
+func findModerationItem(t *testing.T, api *API, chirpID uuid.UUID, status string) moderationItem {
+	t.Helper()
+	query := url.Values{"status": {status}, "limit": {"100"}}
+	for {
+		r := newTestRequest(t, "GET", "/admin/moderation?"+query.Encode(), nil)
+		page := decodeResponse[moderationPage](t, serve(t, api.GetModerationQueue, r, http.StatusOK))
+		for _, item := range page.Items {
+			if item.Status != status {
+				t.Fatalf("GetModerationQueue(%q) returned a %q item", status, item.Status)
+			}
+			if item.ChirpID != nil && *item.ChirpID == chirpID {
+				return item
+			}
+		}
+		if page.NextCursor == "" {
+			t.Fatalf("no %s moderation item for chirp %v", status, chirpID)
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// viewTestChirp gets a chirp as the viewer, or as an anonymous viewer if viewer is nil.
+func viewTestChirp(t *testing.T, api *API, chirpID uuid.UUID, viewer *database.User, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newTestRequest(t, "GET", "/api/chirps/"+chirpID.String(), nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	if viewer != nil {
+		r = asUser(r, *viewer)
+	}
+	return serve(t, api.GetChirpByID, r, wantStatus)
+}
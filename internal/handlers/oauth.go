@@ -0,0 +1,741 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// authorizationCodeLifetime is how long a client has to exchange an authorization code for a token
+const authorizationCodeLifetime = 5 * time.Minute
+
+// oauthError is an error response from an OAuth endpoint, as in RFC 6749 section 5.2.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	// errInvalidClient is returned when a client can't be authenticated.
+	errInvalidClient = &oauthError{Code: "invalid_client", Description: "client authentication failed"}
+	// errInvalidGrant is returned when an authorization code is unknown, expired, or wasn't issued
+	// to the client, for the redirect URI, or with the PKCE challenge it is presented with.
+	errInvalidGrant = &oauthError{Code: "invalid_grant", Description: "invalid or expired authorization code"}
+	// errAuthorizationCodeReused is returned when an authorization code that has already been exchanged is presented again.
+	errAuthorizationCodeReused = &oauthError{Code: "invalid_grant", Description: "authorization code has already been used"}
+)
+
+func respondWithOAuthError(w http.ResponseWriter, statusCode int, funcName string, err *oauthError) {
+	log.Printf("%s: %v", funcName, err)
+	if err == errInvalidClient {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	respondWithJSON(w, statusCode, err)
+}
+
+// oauthClient is a third-party app registered to use Chirpy on behalf of its users.
+type oauthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	// ClientSecret is only shown when a confidential client is registered
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func newOAuthClient(row database.OauthClient) oauthClient {
+	return oauthClient{
+		ID:           row.ID,
+		CreatedAt:    row.CreatedAt,
+		Name:         row.Name,
+		RedirectURIs: row.RedirectUris,
+		Confidential: row.SecretHash.Valid,
+	}
+}
+
+// validateRedirectURI checks a redirect URI a client is registered with. Codes are sent to it, so it must
+// use https, other than on a loopback address for apps running on the user's own machine.
+func validateRedirectURI(redirectURI string) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return errors.New("redirect URI must be absolute")
+	}
+	if u.Fragment != "" {
+		return errors.New("redirect URI must not have a fragment")
+	}
+
+	if u.Scheme == "https" {
+		return nil
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return errors.New("redirect URI must use https, or http on a loopback address")
+}
+
+// parseOAuthScopes parses the space separated scopes a client asks for.
+// At least one is needed, as a token without a scope claim would have every scope.
+func parseOAuthScopes(scope string) ([]string, error) {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(auth.ThirdPartyScopes, s) {
+			return nil, &oauthError{Code: "invalid_scope", Description: fmt.Sprintf("unknown scope %q", s)}
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, &oauthError{Code: "invalid_scope", Description: "scope is required"}
+	}
+	return scopes, nil
+}
+
+// authorizationRequest is a client's request for a user's consent, as in RFC 6749 section 4.1.1.
+// PKCE is required of every client, as in RFC 7636.
+type authorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+func authorizationRequestFromQuery(query url.Values) authorizationRequest {
+	return authorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+}
+
+// validate checks the parts of the request that don't depend on the client, returning the client ID and the scopes asked for.
+func (req authorizationRequest) validate() (uuid.UUID, []string, error) {
+	clientID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		return uuid.Nil, nil, &oauthError{Code: "invalid_request", Description: "client_id must be a registered client ID"}
+	}
+	if req.ResponseType != "code" {
+		return uuid.Nil, nil, &oauthError{Code: "unsupported_response_type", Description: "response_type must be code"}
+	}
+	if req.CodeChallengeMethod != "S256" || !auth.ValidPKCEChallenge(req.CodeChallenge) {
+		return uuid.Nil, nil, &oauthError{Code: "invalid_request", Description: "an S256 PKCE code_challenge is required"}
+	}
+	scopes, err := parseOAuthScopes(req.Scope)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return clientID, scopes, nil
+}
+
+// checkAuthorizationRequest validates an authorization request, returning the client and the scopes it asks for.
+// Errors about the request are returned as an *oauthError.
+func (api *API) checkAuthorizationRequest(ctx context.Context, req authorizationRequest) (database.OauthClient, []string, error) {
+	clientID, scopes, err := req.validate()
+	if err != nil {
+		return database.OauthClient{}, nil, err
+	}
+
+	client, err := api.DB.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.OauthClient{}, nil, &oauthError{Code: "invalid_request", Description: "unknown client_id"}
+		}
+		return database.OauthClient{}, nil, fmt.Errorf("getting OAuth client: %v", err)
+	}
+	// Exact matching stops a code being sent anywhere the client didn't register
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		return database.OauthClient{}, nil, &oauthError{Code: "invalid_request", Description: "redirect_uri isn't registered for this client"}
+	}
+	return client, scopes, nil
+}
+
+// authorizationRedirect adds params to the query of a client's redirect URI.
+func authorizationRedirect(redirectURI string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// authenticateOAuthClient authenticates the client making a token or introspection request,
+// by HTTP basic auth or client_id and client_secret form values. Public clients have no secret to check.
+func (api *API) authenticateOAuthClient(r *http.Request) (database.OauthClient, error) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return database.OauthClient{}, errInvalidClient
+	}
+	client, err := api.DB.GetOAuthClient(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.OauthClient{}, errInvalidClient
+		}
+		return database.OauthClient{}, fmt.Errorf("getting OAuth client: %v", err)
+	}
+
+	if client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashRefreshToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errInvalidClient
+	}
+	return client, nil
+}
+
+// RegisterOAuthClient registers a third-party app, owned by the authenticated user.
+// Confidential clients, which run on a server, are given a secret. It is only shown this once.
+func (api *API) RegisterOAuthClient(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "RegisterOAuthClient: couldn't decode parameters", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "RegisterOAuthClient: no authenticated user in request context", nil)
+		return
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "RegisterOAuthClient: name is required", nil)
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "RegisterOAuthClient: at least one redirect URI is required", nil)
+		return
+	}
+	for _, redirectURI := range params.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			respondWithError(w, http.StatusBadRequest, "RegisterOAuthClient: invalid redirect URI", err)
+			return
+		}
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if params.Confidential {
+		var err error
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "RegisterOAuthClient: couldn't make client secret", err)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashRefreshToken(secret), Valid: true}
+	}
+
+	row, err := api.DB.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RegisterOAuthClient: couldn't store client in DB", err)
+		return
+	}
+
+	client := newOAuthClient(row)
+	client.ClientSecret = secret
+	respondWithJSON(w, http.StatusCreated, client)
+}
+
+// GetOAuthClients lists the third-party apps the authenticated user has registered.
+func (api *API) GetOAuthClients(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetOAuthClients: no authenticated user in request context", nil)
+		return
+	}
+
+	rows, err := api.DB.GetUserOAuthClients(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetOAuthClients: couldn't get clients from DB", err)
+		return
+	}
+
+	clients := make([]oauthClient, len(rows))
+	for i, row := range rows {
+		clients[i] = newOAuthClient(row)
+	}
+
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+// GetOAuthConsent checks an authorization request a client has sent the user to Chirpy with,
+// returning what the consent screen shows them: which app is asking, and for which scopes.
+func (api *API) GetOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ClientID   uuid.UUID `json:"client_id"`
+		ClientName string    `json:"client_name"`
+		Scopes     []string  `json:"scopes"`
+		// AlreadyGranted is true if the user has consented to all of the scopes before
+		AlreadyGranted bool `json:"already_granted"`
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetOAuthConsent: no authenticated user in request context", nil)
+		return
+	}
+
+	client, scopes, err := api.checkAuthorizationRequest(r.Context(), authorizationRequestFromQuery(r.URL.Query()))
+	if err != nil {
+		if oauthErr, ok := err.(*oauthError); ok {
+			respondWithOAuthError(w, http.StatusBadRequest, "GetOAuthConsent", oauthErr)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "GetOAuthConsent: couldn't check authorization request", err)
+		return
+	}
+
+	alreadyGranted := false
+	grant, err := api.DB.GetOAuthGrant(r.Context(), database.GetOAuthGrantParams{
+		UserID:   userID,
+		ClientID: client.ID,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "GetOAuthConsent: couldn't get grant from DB", err)
+		return
+	}
+	if err == nil {
+		alreadyGranted = true
+		for _, scope := range scopes {
+			alreadyGranted = alreadyGranted && slices.Contains(grant.Scopes, scope)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		ClientID:       client.ID,
+		ClientName:     client.Name,
+		Scopes:         scopes,
+		AlreadyGranted: alreadyGranted,
+	})
+}
+
+// AuthorizeOAuthClient records the user's answer on the consent screen. If they approve, the client is
+// granted the scopes, and given an authorization code to exchange for an access token. Either way, it
+// returns where to send the user back to the client, as in RFC 6749 section 4.1.2.
+func (api *API) AuthorizeOAuthClient(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		authorizationRequest
+		Approve bool `json:"approve"`
+	}
+	type response struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "AuthorizeOAuthClient: couldn't decode parameters", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "AuthorizeOAuthClient: no authenticated user in request context", nil)
+		return
+	}
+
+	client, scopes, err := api.checkAuthorizationRequest(r.Context(), params.authorizationRequest)
+	if err != nil {
+		if oauthErr, ok := err.(*oauthError); ok {
+			respondWithOAuthError(w, http.StatusBadRequest, "AuthorizeOAuthClient", oauthErr)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "AuthorizeOAuthClient: couldn't check authorization request", err)
+		return
+	}
+
+	redirectParams := url.Values{}
+	if params.State != "" {
+		redirectParams.Set("state", params.State)
+	}
+
+	if !params.Approve {
+		redirectParams.Set("error", "access_denied")
+	} else {
+		code, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "AuthorizeOAuthClient: couldn't make authorization code", err)
+			return
+		}
+
+		err = api.withTx(r.Context(), func(q *database.Queries) error {
+			if _, err := q.UpsertOAuthGrant(r.Context(), database.UpsertOAuthGrantParams{
+				UserID:   userID,
+				ClientID: client.ID,
+				Scopes:   scopes,
+			}); err != nil {
+				return fmt.Errorf("storing grant: %v", err)
+			}
+			if err := q.DeleteExpiredOAuthAuthorizationCodes(r.Context()); err != nil {
+				return fmt.Errorf("deleting expired authorization codes: %v", err)
+			}
+			return q.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+				CodeHash:      auth.HashRefreshToken(code),
+				ExpiresAt:     time.Now().UTC().Add(authorizationCodeLifetime),
+				ClientID:      client.ID,
+				UserID:        userID,
+				RedirectUri:   params.RedirectURI,
+				Scopes:        scopes,
+				CodeChallenge: params.CodeChallenge,
+			})
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "AuthorizeOAuthClient: couldn't store authorization in DB", err)
+			return
+		}
+		redirectParams.Set("code", code)
+	}
+
+	redirectTo, err := authorizationRedirect(params.RedirectURI, redirectParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "AuthorizeOAuthClient: couldn't build redirect URI", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{RedirectTo: redirectTo})
+}
+
+// ExchangeOAuthToken exchanges an authorization code for an access token, as in RFC 6749 section 4.1.3.
+// The token is made like a login's, but only has the scopes the user consented to, and never a role above user.
+// No refresh token is issued: when the access token expires, the client sends the user through authorization again.
+func (api *API) ExchangeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}
+
+	// Responses with tokens mustn't be cached, as in RFC 6749 section 5.1
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "ExchangeOAuthToken",
+			&oauthError{Code: "invalid_request", Description: "couldn't parse form"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		respondWithOAuthError(w, http.StatusBadRequest, "ExchangeOAuthToken",
+			&oauthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code"})
+		return
+	}
+
+	client, err := api.authenticateOAuthClient(r)
+	if err != nil {
+		if err == errInvalidClient {
+			respondWithOAuthError(w, http.StatusUnauthorized, "ExchangeOAuthToken", errInvalidClient)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "ExchangeOAuthToken: couldn't authenticate client", err)
+		return
+	}
+
+	var code database.OauthAuthorizationCode
+	accessTokenID := uuid.NewString()
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		code, err = q.GetOAuthAuthorizationCodeForUpdate(r.Context(), auth.HashRefreshToken(r.PostForm.Get("code")))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errInvalidGrant
+			}
+			return fmt.Errorf("getting authorization code: %v", err)
+		}
+		if code.ClientID != client.ID {
+			return errInvalidGrant
+		}
+		if code.UsedAt.Valid {
+			return errAuthorizationCodeReused
+		}
+		if time.Now().UTC().After(code.ExpiresAt) ||
+			code.RedirectUri != r.PostForm.Get("redirect_uri") ||
+			!auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			return errInvalidGrant
+		}
+
+		if err := q.UseOAuthAuthorizationCode(r.Context(), code.CodeHash); err != nil {
+			return fmt.Errorf("using authorization code: %v", err)
+		}
+
+		// The user may have revoked the grant since approving it. Locking it makes
+		// RevokeOAuthGrant wait for this token to be recorded, so it is revoked too
+		if _, err := q.GetOAuthGrantForUpdate(r.Context(), database.GetOAuthGrantForUpdateParams{
+			UserID:   code.UserID,
+			ClientID: client.ID,
+		}); err != nil {
+			if err == sql.ErrNoRows {
+				return errInvalidGrant
+			}
+			return fmt.Errorf("getting grant: %v", err)
+		}
+
+		if err := q.DeleteExpiredOAuthAccessTokens(r.Context(), time.Now().UTC().Add(-(accessTokenLifetime + auth.ClockSkewLeeway))); err != nil {
+			return fmt.Errorf("deleting expired access tokens: %v", err)
+		}
+		return q.CreateOAuthAccessToken(r.Context(), database.CreateOAuthAccessTokenParams{
+			ID:       accessTokenID,
+			ClientID: client.ID,
+			UserID:   code.UserID,
+		})
+	})
+	if err != nil {
+		if err == errAuthorizationCodeReused {
+			// Either the client or someone who stole the code has already used it,
+			// so the tokens it was exchanged for can't be trusted
+			if revokeErr := api.revokeOAuthAccessTokens(r.Context(), code.UserID, client.ID); revokeErr != nil {
+				log.Printf("ExchangeOAuthToken: %v", revokeErr)
+			}
+			respondWithOAuthError(w, http.StatusBadRequest, "ExchangeOAuthToken", errAuthorizationCodeReused)
+			return
+		}
+		if err == errInvalidGrant {
+			respondWithOAuthError(w, http.StatusBadRequest, "ExchangeOAuthToken", errInvalidGrant)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "ExchangeOAuthToken: couldn't exchange authorization code", err)
+		return
+	}
+
+	accessToken, err := auth.MakeJWT(api.keyring, auth.TokenOptions{
+		UserID:    code.UserID,
+		Role:      auth.RoleUser,
+		ExpiresIn: accessTokenLifetime,
+		Scopes:    code.Scopes,
+		ID:        accessTokenID,
+		ClientID:  client.ID.String(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "ExchangeOAuthToken: couldn't create access JWT", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(accessTokenLifetime.Seconds()),
+		Scope:       strings.Join(code.Scopes, " "),
+	})
+}
+
+// IntrospectOAuthToken tells a confidential client whether an access token issued to it is still active,
+// and what it allows, as in RFC 7662. Tokens issued to anyone else are reported as inactive.
+func (api *API) IntrospectOAuthToken(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool     `json:"active"`
+		Scope     string   `json:"scope,omitempty"`
+		ClientID  string   `json:"client_id,omitempty"`
+		TokenType string   `json:"token_type,omitempty"`
+		Exp       int64    `json:"exp,omitempty"`
+		Iat       int64    `json:"iat,omitempty"`
+		Nbf       int64    `json:"nbf,omitempty"`
+		Sub       string   `json:"sub,omitempty"`
+		Aud       []string `json:"aud,omitempty"`
+		Iss       string   `json:"iss,omitempty"`
+		Jti       string   `json:"jti,omitempty"`
+	}
+
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "IntrospectOAuthToken",
+			&oauthError{Code: "invalid_request", Description: "couldn't parse form"})
+		return
+	}
+
+	client, err := api.authenticateOAuthClient(r)
+	if err == nil && !client.SecretHash.Valid {
+		// A public client has no secret, so anyone could introspect as it
+		err = errInvalidClient
+	}
+	if err != nil {
+		if err == errInvalidClient {
+			respondWithOAuthError(w, http.StatusUnauthorized, "IntrospectOAuthToken", errInvalidClient)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "IntrospectOAuthToken: couldn't authenticate client", err)
+		return
+	}
+
+	claims, err := auth.ParseJWT(r.PostForm.Get("token"), api.keyring)
+	if err != nil || claims.ClientID != client.ID.String() {
+		respondWithJSON(w, http.StatusOK, response{Active: false})
+		return
+	}
+	revoked, err := api.denylist.IsRevoked(r.Context(), claims.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "IntrospectOAuthToken: couldn't check access token denylist", err)
+		return
+	}
+	if revoked {
+		respondWithJSON(w, http.StatusOK, response{Active: false})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Nbf:       claims.NotBefore.Unix(),
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	})
+}
+
+// oauthGrant is a third-party app the user has given access to their account.
+type oauthGrant struct {
+	ClientID   uuid.UUID `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GetOAuthGrants lists the third-party apps the authenticated user has given access to their account.
+func (api *API) GetOAuthGrants(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetOAuthGrants: no authenticated user in request context", nil)
+		return
+	}
+
+	rows, err := api.DB.GetUserOAuthGrants(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetOAuthGrants: couldn't get grants from DB", err)
+		return
+	}
+
+	grants := make([]oauthGrant, len(rows))
+	for i, row := range rows {
+		grants[i] = oauthGrant{
+			ClientID:   row.ClientID,
+			ClientName: row.ClientName,
+			Scopes:     row.Scopes,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, grants)
+}
+
+// RevokeOAuthGrant takes away a third-party app's access to the authenticated user's account,
+// revoking any access tokens and authorization codes it holds.
+func (api *API) RevokeOAuthGrant(w http.ResponseWriter, r *http.Request) {
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "RevokeOAuthGrant: couldn't parse path value 'clientID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "RevokeOAuthGrant: no authenticated user in request context", nil)
+		return
+	}
+
+	var deleted int64
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		deleted, err = q.DeleteOAuthGrant(r.Context(), database.DeleteOAuthGrantParams{
+			UserID:   userID,
+			ClientID: clientID,
+		})
+		if err != nil {
+			return fmt.Errorf("deleting grant: %v", err)
+		}
+		if err := q.DeleteOAuthAuthorizationCodes(r.Context(), database.DeleteOAuthAuthorizationCodesParams{
+			UserID:   userID,
+			ClientID: clientID,
+		}); err != nil {
+			return fmt.Errorf("deleting authorization codes: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeOAuthGrant: couldn't delete grant in DB", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "RevokeOAuthGrant: no grant found for the given client", nil)
+		return
+	}
+
+	if err := api.revokeOAuthAccessTokens(r.Context(), userID, clientID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeOAuthGrant: couldn't revoke access tokens", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// revokeOAuthAccessTokens denylists the access tokens issued to a client for a user that may still be valid.
+func (api *API) revokeOAuthAccessTokens(ctx context.Context, userID, clientID uuid.UUID) error {
+	tokens, err := api.DB.GetOAuthGrantAccessTokens(ctx, database.GetOAuthGrantAccessTokensParams{
+		UserID:    userID,
+		ClientID:  clientID,
+		CreatedAt: time.Now().UTC().Add(-(accessTokenLifetime + auth.ClockSkewLeeway)),
+	})
+	if err != nil {
+		return fmt.Errorf("getting access tokens of grant: %v", err)
+	}
+
+	for _, token := range tokens {
+		if err := api.revokeAccessToken(ctx, token.ID, token.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
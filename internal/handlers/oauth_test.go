@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		redirectURI string
+		wantErr     bool
+	}{
+		{"https://app.example.com/callback", false},
+		{"https://app.example.com/callback?source=chirpy", false},
+		{"http://localhost:3000/callback", false},
+		{"http://127.0.0.1:3000/callback", false},
+		{"http://[::1]/callback", false},
+		{"http://app.example.com/callback", true},
+		{"https://app.example.com/callback#fragment", true},
+		{"/callback", true},
+		{"javascript:alert(1)", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.redirectURI, func(t *testing.T) {
+			if err := validateRedirectURI(tt.redirectURI); (err != nil) != tt.wantErr {
+				t.Errorf("validateRedirectURI(%q) error = %v, wantErr %v", tt.redirectURI, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOAuthScopes(t *testing.T) {
+	tests := []struct {
+		scope   string
+		want    []string
+		wantErr bool
+	}{
+		{"chirps:read", []string{auth.ScopeChirpsRead}, false},
+		{"chirps:read  chirps:write chirps:read", []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite}, false},
+		{"follows:write", []string{auth.ScopeFollowsWrite}, false},
+		{"account:write", nil, true},
+		{"chirps:read admin", nil, true},
+		{"", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			got, err := parseOAuthScopes(tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOAuthScopes(%q) error = %v, wantErr %v", tt.scope, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseOAuthScopes(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationRequestValidate(t *testing.T) {
+	clientID := uuid.New()
+	valid := authorizationRequest{
+		ResponseType:        "code",
+		ClientID:            clientID.String(),
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "chirps:read",
+		State:               "xyz",
+		CodeChallenge:       auth.PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"),
+		CodeChallengeMethod: "S256",
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(req *authorizationRequest)
+		wantCode string
+	}{
+		{"valid", func(req *authorizationRequest) {}, ""},
+		{"invalid client ID", func(req *authorizationRequest) { req.ClientID = "app" }, "invalid_request"},
+		{"implicit grant", func(req *authorizationRequest) { req.ResponseType = "token" }, "unsupported_response_type"},
+		{"no PKCE", func(req *authorizationRequest) { req.CodeChallenge, req.CodeChallengeMethod = "", "" }, "invalid_request"},
+		{"plain PKCE", func(req *authorizationRequest) { req.CodeChallengeMethod = "plain" }, "invalid_request"},
+		{"no scope", func(req *authorizationRequest) { req.Scope = "" }, "invalid_scope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+
+			gotClientID, _, err := req.validate()
+			if tt.wantCode == "" {
+				if err != nil || gotClientID != clientID {
+					t.Errorf("validate() = %v, %v, want %v, nil", gotClientID, err, clientID)
+				}
+				return
+			}
+			oauthErr, ok := err.(*oauthError)
+			if !ok || oauthErr.Code != tt.wantCode {
+				t.Errorf("validate() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAuthorizationRedirect(t *testing.T) {
+	tests := []struct {
+		redirectURI string
+		params      url.Values
+		want        string
+	}{
+		{
+			redirectURI: "https://app.example.com/callback",
+			params:      url.Values{"code": {"abc"}, "state": {"xyz"}},
+			want:        "https://app.example.com/callback?code=abc&state=xyz",
+		},
+		{
+			redirectURI: "https://app.example.com/callback?source=chirpy",
+			params:      url.Values{"error": {"access_denied"}},
+			want:        "https://app.example.com/callback?error=access_denied&source=chirpy",
+		},
+		{
+			redirectURI: "http://localhost:3000/callback",
+			params:      url.Values{"state": {"a b&c"}, "code": {"abc"}},
+			want:        "http://localhost:3000/callback?code=abc&state=a+b%26c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.redirectURI, func(t *testing.T) {
+			got, err := authorizationRedirect(tt.redirectURI, tt.params)
+			if err != nil {
+				t.Fatalf("authorizationRedirect() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("authorizationRedirect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+const (
+	// oidcLoginLifetime is how long a user has to log in at the provider and come back
+	oidcLoginLifetime = 10 * time.Minute
+	// oidcBindingCookie holds a secret that binds a login to the client that began it
+	oidcBindingCookie = "chirpy_oidc_login"
+	oidcBindingPath   = "/api/login/oidc"
+)
+
+var (
+	// errOIDCEmailUnverified is returned when a provider identity can't be linked to a user,
+	// as the provider hasn't verified its email address.
+	errOIDCEmailUnverified = errors.New("provider hasn't verified the email address")
+	// errOIDCAccountUnverified is returned when a provider identity's email belongs to a user who has never verified it.
+	// Linking could hand the account to whoever registered it, if they aren't the owner of the email address.
+	errOIDCAccountUnverified = errors.New("account email address isn't verified")
+)
+
+// BeginOIDCLogin starts a login through the OpenID Connect provider. The client sends the user to the
+// returned URL, and once the provider sends them back, passes the code and state to FinishOIDCLogin.
+// The login is bound to the client by a cookie, so a state and code from someone else's login can't be
+// finished by the client, logging it in to their account.
+func (api *API) BeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		AuthorizationURL string `json:"authorization_url"`
+		State            string `json:"state"`
+	}
+
+	if api.oidc == nil {
+		respondWithError(w, http.StatusNotFound, "BeginOIDCLogin: OIDC login isn't configured", nil)
+		return
+	}
+
+	// Random hex from MakeRefreshToken is also a valid PKCE code verifier
+	values := make([]string, 4)
+	for i := range values {
+		value, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "BeginOIDCLogin: couldn't make login state", err)
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier, binding := values[0], values[1], values[2], values[3]
+
+	if err := api.DB.DeleteExpiredOIDCLogins(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginOIDCLogin: couldn't delete expired logins in DB", err)
+		return
+	}
+	if err := api.DB.CreateOIDCLogin(r.Context(), database.CreateOIDCLoginParams{
+		State:        state,
+		ExpiresAt:    time.Now().UTC().Add(oidcLoginLifetime),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BindingHash:  auth.HashRefreshToken(binding),
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginOIDCLogin: couldn't store login in DB", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		Path:     oidcBindingPath,
+		MaxAge:   int(oidcLoginLifetime.Seconds()),
+		Secure:   api.platform != "dev",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	respondWithJSON(w, http.StatusOK, response{
+		AuthorizationURL: api.oidc.AuthCodeURL(state, nonce, verifier),
+		State:            state,
+	})
+}
+
+// FinishOIDCLogin completes a login begun by BeginOIDCLogin, given the code and state the provider sent the user back with.
+// The provider's identity is linked to the user with its verified email address the first time, creating one if needed.
+// The user is then logged in as LoginUser would, so users with two-factor authentication still need their code.
+func (api *API) FinishOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+
+	if api.oidc == nil {
+		respondWithError(w, http.StatusNotFound, "FinishOIDCLogin: OIDC login isn't configured", nil)
+		return
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishOIDCLogin: couldn't decode parameters", err)
+		return
+	}
+
+	binding, err := r.Cookie(oidcBindingCookie)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "FinishOIDCLogin: login wasn't begun by this client", err)
+		return
+	}
+	// The login is finished or abandoned either way, so the cookie isn't needed again
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcBindingCookie,
+		Path:     oidcBindingPath,
+		MaxAge:   -1,
+		Secure:   api.platform != "dev",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Each state can only be used once, so a code can't be replayed through it.
+	// A state from a login begun by another client isn't found, so isn't used up either
+	login, err := api.DB.TakeOIDCLogin(r.Context(), database.TakeOIDCLoginParams{
+		State:       params.State,
+		BindingHash: auth.HashRefreshToken(binding.Value),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "FinishOIDCLogin: invalid or expired login state", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FinishOIDCLogin: couldn't get login from DB", err)
+		return
+	}
+
+	identity, err := api.oidc.Exchange(r.Context(), params.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "FinishOIDCLogin: couldn't verify identity with provider", err)
+		return
+	}
+
+	user, err := api.oidcUser(r.Context(), identity)
+	if err != nil {
+		if err == errOIDCEmailUnverified {
+			respondWithError(w, http.StatusForbidden, "FinishOIDCLogin: provider hasn't verified your email address", err)
+			return
+		}
+		if err == errOIDCAccountUnverified {
+			respondWithError(w, http.StatusConflict, "FinishOIDCLogin: an account with this email address exists, but hasn't verified it", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FinishOIDCLogin: couldn't get user for identity", err)
+		return
+	}
+
+	api.startLogin(w, r, "FinishOIDCLogin", user)
+}
+
+// oidcUser returns the user a provider identity is linked to. An identity seen for the first time is linked to
+// the user with its email address, or a new user if there isn't one, as long as the provider has verified it.
+func (api *API) oidcUser(ctx context.Context, identity auth.OIDCIdentity) (database.User, error) {
+	var user database.User
+	err := api.withTx(ctx, func(q *database.Queries) error {
+		linked, err := q.GetUserIdentity(ctx, database.GetUserIdentityParams{
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+		})
+		if err == nil {
+			user, err = q.GetUserByID(ctx, linked.UserID)
+			if err != nil {
+				return fmt.Errorf("getting linked user: %v", err)
+			}
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("getting identity: %v", err)
+		}
+
+		if identity.Email == "" || !identity.EmailVerified {
+			return errOIDCEmailUnverified
+		}
+
+		user, err = q.GetUserByEmail(ctx, identity.Email)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("getting user by email: %v", err)
+		}
+		if err == nil && !user.EmailVerifiedAt.Valid {
+			return errOIDCAccountUnverified
+		}
+		if err == sql.ErrNoRows {
+			user, err = createOIDCUser(ctx, q, identity.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			UserID:  user.ID,
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+			Email:   identity.Email,
+		}); err != nil {
+			return fmt.Errorf("linking identity: %v", err)
+		}
+		return nil
+	})
+	return user, err
+}
+
+// createOIDCUser creates a user for a provider identity. Their email address is already verified by the provider.
+// Their password is random, so they can only log in through the provider, until they reset it.
+func createOIDCUser(ctx context.Context, q *database.Queries, email string) (database.User, error) {
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.User{}, fmt.Errorf("making password: %v", err)
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return database.User{}, fmt.Errorf("hashing password: %v", err)
+	}
+
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return database.User{}, fmt.Errorf("creating user: %v", err)
+	}
+	if _, err := q.SetEmailVerified(ctx, database.SetEmailVerifiedParams{
+		ID:    user.ID,
+		Email: email,
+	}); err != nil {
+		return database.User{}, fmt.Errorf("verifying email: %v", err)
+	}
+	return user, nil
+}
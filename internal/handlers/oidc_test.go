@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect provider, where every user who is sent to it logs in straight away.
+type mockOIDCProvider struct {
+	*httptest.Server
+	key     *rsa.PrivateKey
+	keyring *auth.Keyring
+
+	mu sync.Mutex
+	// logins are the logins waiting for their code to be exchanged, by code
+	logins map[string]mockOIDCLogin
+	// exchanges counts the codes sent to be exchanged
+	exchanges int
+}
+
+type mockOIDCLogin struct {
+	email         string
+	nonce         string
+	codeChallenge string
+}
+
+const mockOIDCClientID = "chirpy"
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	keyring, err := auth.NewKeyring(auth.NewRSAKey("mock", key))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	m := &mockOIDCProvider{key: key, keyring: keyring, logins: make(map[string]mockOIDCLogin)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]any{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{auth.AlgRS256},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, m.keyring.JWKS())
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.exchanges++
+		login, ok := m.logins[r.PostFormValue("code")]
+		delete(m.logins, r.PostFormValue("code"))
+		m.mu.Unlock()
+		if !ok || !auth.VerifyPKCE(r.PostFormValue("code_verifier"), login.codeChallenge) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeMockJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.URL,
+			"sub":            login.email,
+			"aud":            mockOIDCClientID,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          login.nonce,
+			"email":          login.email,
+			"email_verified": true,
+		})
+		token.Header["kid"] = "mock"
+		idToken, err := token.SignedString(m.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeMockJSON(w, map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize logs a user in at the provider, returning the code it sends them back with.
+func (m *mockOIDCProvider) authorize(t *testing.T, authorizationURL string, email string) string {
+	t.Helper()
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("authorization URL %q isn't a URL: %v", authorizationURL, err)
+	}
+	code := uuid.NewString()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logins[code] = mockOIDCLogin{
+		email:         email,
+		nonce:         u.Query().Get("nonce"),
+		codeChallenge: u.Query().Get("code_challenge"),
+	}
+	return code
+}
+
+func (m *mockOIDCProvider) exchanged() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exchanges
+}
+
+func writeMockJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type oidcLogin struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	// cookie is the cookie binding the login to the client that began it
+	cookie *http.Cookie
+}
+
+func TestFinishOIDCLogin(t *testing.T) {
+	api, _ := newTestAPI(t)
+	provider := newMockOIDCProvider(t)
+	var err error
+	api.oidc, err = auth.NewOIDCProvider(context.Background(), provider.URL, mockOIDCClientID, "secret", "http://localhost:8080/oidc/callback")
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// cookie returns the cookie the client finishes its login with, given the one it was sent and one from someone else's login
+		cookie        func(own *http.Cookie, other *http.Cookie) *http.Cookie
+		wantStatus    int
+		wantExchanged bool
+	}{
+		{
+			name:          "cookie from the login",
+			cookie:        func(own *http.Cookie, other *http.Cookie) *http.Cookie { return own },
+			wantStatus:    http.StatusOK,
+			wantExchanged: true,
+		},
+		{
+			name:       "no cookie",
+			cookie:     func(own *http.Cookie, other *http.Cookie) *http.Cookie { return nil },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "cookie from another login",
+			cookie:     func(own *http.Cookie, other *http.Cookie) *http.Cookie { return other },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login := beginOIDCLogin(t, api)
+			// Someone else begins a login, and tricks the client into finishing it, with their own code
+			other := beginOIDCLogin(t, api)
+			code := provider.authorize(t, login.AuthorizationURL, uuid.NewString()+"@example.com")
+
+			exchanged := provider.exchanged()
+			finishOIDCLogin(t, api, code, login.State, tt.cookie(login.cookie, other.cookie), tt.wantStatus)
+			if got := provider.exchanged() > exchanged; got != tt.wantExchanged {
+				t.Errorf("code exchanged = %v, want %v", got, tt.wantExchanged)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+
+			// The login wasn't used up, so the client that began it can still finish it
+			finishOIDCLogin(t, api, code, login.State, login.cookie, http.StatusOK)
+		})
+	}
+}
+
+func beginOIDCLogin(t *testing.T, api *API) oidcLogin {
+	t.Helper()
+	w := serve(t, api.BeginOIDCLogin, newTestRequest(t, "POST", "/api/login/oidc/begin", nil), http.StatusOK)
+	login := decodeResponse[oidcLogin](t, w)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcBindingCookie {
+			login.cookie = cookie
+		}
+	}
+	if login.cookie == nil || !login.cookie.HttpOnly {
+		t.Fatalf("BeginOIDCLogin() cookie = %+v, want an HttpOnly %s cookie", login.cookie, oidcBindingCookie)
+	}
+	return login
+}
+
+func finishOIDCLogin(t *testing.T, api *API, code string, state string, cookie *http.Cookie, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/login/oidc/finish", map[string]string{"code": code, "state": state})
+	if cookie != nil {
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	serve(t, api.FinishOIDCLogin, r, wantStatus)
+}
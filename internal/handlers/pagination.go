@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor marks the last row of a page for keyset pagination on (created_at, id).
+// It is handed to clients as an opaque string.
+type pageCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	dat, err := json.Marshal(pageCursor{CreatedAt: createdAt, ID: id})
+	if err != nil {
+		// Marshalling a time and a UUID can't fail
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+// decodeCursor parses a cursor from a query string. An empty string returns a nil cursor, meaning the first page.
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding cursor: %v", err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(dat, &c); err != nil {
+		return nil, fmt.Errorf("unmarshalling cursor: %v", err)
+	}
+	if c.ID == uuid.Nil || c.CreatedAt.IsZero() {
+		return nil, errors.New("cursor is missing fields")
+	}
+	return &c, nil
+}
+
+func (c *pageCursor) createdAt() sql.NullTime {
+	if c == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}
+}
+
+func (c *pageCursor) id() uuid.NullUUID {
+	if c == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+// parsePageSize parses the 'limit' query param, falling back to the default when empty.
+func parsePageSize(s string) (int32, error) {
+	if s == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing limit %q: %v", s, err)
+	}
+	if limit < 1 || limit > maxPageSize {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	return int32(limit), nil
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	id := uuid.New()
+	createdAt := time.Date(2025, 12, 10, 8, 15, 42, 123456000, time.UTC)
+	validCursor := encodeCursor(createdAt, id)
+
+	tests := []struct {
+		name    string
+		cursor  string
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "Valid cursor",
+			cursor:  validCursor,
+			wantNil: false,
+			wantErr: false,
+		},
+		{
+			name:    "Empty cursor",
+			cursor:  "",
+			wantNil: true,
+			wantErr: false,
+		},
+		{
+			name:    "Not base64",
+			cursor:  "not a cursor!",
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "Missing fields",
+			cursor:  "e30",
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("decodeCursor() got = %v, wantNil %v", got, tt.wantNil)
+				return
+			}
+			if got != nil && (got.ID != id || !got.CreatedAt.Equal(createdAt)) {
+				t.Errorf("decodeCursor() got = %v, want {%v %v}", got, createdAt, id)
+			}
+		})
+	}
+}
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		want    int32
+		wantErr bool
+	}{
+		{
+			name:    "Empty uses default",
+			limit:   "",
+			want:    defaultPageSize,
+			wantErr: false,
+		},
+		{
+			name:    "Valid limit",
+			limit:   "50",
+			want:    50,
+			wantErr: false,
+		},
+		{
+			name:    "Zero",
+			limit:   "0",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Above max",
+			limit:   "101",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "Not a number",
+			limit:   "ten",
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePageSize(tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePageSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parsePageSize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
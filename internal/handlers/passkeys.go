@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// passkeyCeremonyLifetime is how long a user has to answer a passkey challenge
+const passkeyCeremonyLifetime = 5 * time.Minute
+
+var (
+	// errPasskeyCeremony is returned when a passkey ceremony is finished with a session that
+	// doesn't exist, has expired, or was begun for a different user or ceremony.
+	errPasskeyCeremony = errors.New("invalid or expired passkey session")
+	// errPasskeyCloned is returned when a passkey's signature counter goes backwards,
+	// meaning the private key may have been copied off the authenticator.
+	errPasskeyCloned = errors.New("passkey may have been cloned")
+)
+
+// passkey is a WebAuthn credential registered to a user, as shown to them.
+type passkey struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	CloneWarning   bool       `json:"clone_warning"`
+}
+
+func newPasskey(row database.WebauthnCredential) passkey {
+	p := passkey{
+		ID:             row.ID,
+		CreatedAt:      row.CreatedAt,
+		BackupEligible: row.BackupEligible,
+		BackupState:    row.BackupState,
+		CloneWarning:   row.CloneWarning,
+	}
+	if row.LastUsedAt.Valid {
+		p.LastUsedAt = &row.LastUsedAt.Time
+	}
+	return p
+}
+
+// passkeyCeremony is the response to beginning a ceremony. The options are passed to
+// navigator.credentials.create() or .get(), and the session ID sent back with the result.
+type passkeyCeremony struct {
+	SessionID uuid.UUID `json:"session_id"`
+	Options   any       `json:"options"`
+}
+
+// passkeyUser adapts a user and their stored credentials to webauthn.User.
+type passkeyUser struct {
+	user        database.User
+	credentials []database.WebauthnCredential
+}
+
+// WebAuthnID is the user handle stored on the authenticator, which a discoverable login sends back.
+func (u *passkeyUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, row := range u.credentials {
+		credentials[i] = webAuthnCredential(row)
+	}
+	return credentials
+}
+
+// credential returns the stored credential with the given WebAuthn credential ID.
+func (u *passkeyUser) credential(id []byte) (database.WebauthnCredential, bool) {
+	for _, row := range u.credentials {
+		if bytes.Equal(row.CredentialID, id) {
+			return row, true
+		}
+	}
+	return database.WebauthnCredential{}, false
+}
+
+// webAuthnCredential converts a stored credential to the form the webauthn library verifies against.
+func webAuthnCredential(row database.WebauthnCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, len(row.Transports))
+	for i, transport := range row.Transports {
+		transports[i] = protocol.AuthenticatorTransport(transport)
+	}
+
+	return webauthn.Credential{
+		ID:              row.CredentialID,
+		PublicKey:       row.PublicKey,
+		AttestationType: row.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: row.BackupEligible,
+			BackupState:    row.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       row.Aaguid,
+			SignCount:    uint32(row.SignCount),
+			CloneWarning: row.CloneWarning,
+		},
+	}
+}
+
+// newWebAuthnCredentialParams converts a newly registered credential to the form it's stored in.
+func newWebAuthnCredentialParams(userID uuid.UUID, credential *webauthn.Credential) database.CreateWebAuthnCredentialParams {
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	return database.CreateWebAuthnCredentialParams{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Aaguid:          credential.Authenticator.AAGUID,
+		Transports:      transports,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
+
+// getPasskeyUser loads a user along with their registered passkeys.
+func getPasskeyUser(ctx context.Context, q *database.Queries, userID uuid.UUID) (*passkeyUser, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting user: %v", err)
+	}
+	credentials, err := q.GetUserWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting passkeys: %v", err)
+	}
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+// beginPasskeyCeremony stores the session for a ceremony the finish request must complete.
+// userID is only set for registration, as a login doesn't know who the user is until the passkey says.
+func (api *API) beginPasskeyCeremony(ctx context.Context, userID uuid.NullUUID, session *webauthn.SessionData) (uuid.UUID, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("encoding passkey session: %v", err)
+	}
+
+	if err := api.DB.DeleteExpiredWebAuthnSessions(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("deleting expired passkey sessions: %v", err)
+	}
+
+	row, err := api.DB.CreateWebAuthnSession(ctx, database.CreateWebAuthnSessionParams{
+		ExpiresAt: time.Now().UTC().Add(passkeyCeremonyLifetime),
+		UserID:    userID,
+		Data:      data,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("storing passkey session: %v", err)
+	}
+	return row.ID, nil
+}
+
+// finishPasskeyCeremony takes the session stored by beginPasskeyCeremony, so each challenge can be answered once.
+// It returns errPasskeyCeremony unless the session was begun for userID.
+func (api *API) finishPasskeyCeremony(ctx context.Context, sessionID uuid.UUID, userID uuid.NullUUID) (webauthn.SessionData, error) {
+	row, err := api.DB.TakeWebAuthnSession(ctx, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return webauthn.SessionData{}, errPasskeyCeremony
+		}
+		return webauthn.SessionData{}, fmt.Errorf("getting passkey session: %v", err)
+	}
+	if row.UserID != userID {
+		return webauthn.SessionData{}, errPasskeyCeremony
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(row.Data, &session); err != nil {
+		return webauthn.SessionData{}, fmt.Errorf("decoding passkey session: %v", err)
+	}
+	return session, nil
+}
+
+// BeginPasskeyRegistration starts registering a passkey for the authenticated user.
+// The passkey must be discoverable and verify the user, so it can log in on its own.
+func (api *API) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "BeginPasskeyRegistration: no authenticated user in request context", nil)
+		return
+	}
+
+	user, err := getPasskeyUser(r.Context(), api.DB, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginPasskeyRegistration: couldn't get user from DB", err)
+		return
+	}
+
+	creation, session, err := api.passkeys.BeginRegistration(user,
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		}),
+		// Stops the same authenticator being registered twice
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginPasskeyRegistration: couldn't begin registration", err)
+		return
+	}
+
+	sessionID, err := api.beginPasskeyCeremony(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, session)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginPasskeyRegistration: couldn't store passkey session in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, passkeyCeremony{SessionID: sessionID, Options: creation})
+}
+
+// FinishPasskeyRegistration verifies the authenticator's response to BeginPasskeyRegistration and stores the new passkey.
+func (api *API) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		SessionID  uuid.UUID       `json:"session_id"`
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishPasskeyRegistration: couldn't decode parameters", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "FinishPasskeyRegistration: no authenticated user in request context", nil)
+		return
+	}
+
+	session, err := api.finishPasskeyCeremony(r.Context(), params.SessionID, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		if err == errPasskeyCeremony {
+			respondWithError(w, http.StatusBadRequest, "FinishPasskeyRegistration: invalid or expired passkey session", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FinishPasskeyRegistration: couldn't get passkey session from DB", err)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(params.Credential)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishPasskeyRegistration: couldn't parse credential", err)
+		return
+	}
+
+	user, err := getPasskeyUser(r.Context(), api.DB, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "FinishPasskeyRegistration: couldn't get user from DB", err)
+		return
+	}
+
+	// Checks the challenge, and that the origin and RP ID are ours, so a passkey can't be registered through a lookalike site
+	credential, err := api.passkeys.CreateCredential(user, session, parsed)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishPasskeyRegistration: couldn't verify credential", err)
+		return
+	}
+
+	row, err := api.DB.CreateWebAuthnCredential(r.Context(), newWebAuthnCredentialParams(userID, credential))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "FinishPasskeyRegistration: couldn't store passkey in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newPasskey(row))
+}
+
+// GetPasskeys lists the authenticated user's passkeys.
+func (api *API) GetPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetPasskeys: no authenticated user in request context", nil)
+		return
+	}
+
+	rows, err := api.DB.GetUserWebAuthnCredentials(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetPasskeys: couldn't get passkeys from DB", err)
+		return
+	}
+
+	passkeys := make([]passkey, len(rows))
+	for i, row := range rows {
+		passkeys[i] = newPasskey(row)
+	}
+
+	respondWithJSON(w, http.StatusOK, passkeys)
+}
+
+// DeletePasskey removes one of the authenticated user's passkeys, so it can no longer log in.
+func (api *API) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	passkeyID, err := uuid.Parse(r.PathValue("passkeyID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "DeletePasskey: couldn't parse path value 'passkeyID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "DeletePasskey: no authenticated user in request context", nil)
+		return
+	}
+
+	deleted, err := api.DB.DeleteWebAuthnCredential(r.Context(), database.DeleteWebAuthnCredentialParams{
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "DeletePasskey: couldn't delete passkey in DB", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "DeletePasskey: no passkey found for the given ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// BeginPasskeyLogin starts a passkey login. No email is needed: the authenticator offers the
+// user their passkeys for this site, and the one they pick identifies them.
+func (api *API) BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, session, err := api.passkeys.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginPasskeyLogin: couldn't begin login", err)
+		return
+	}
+
+	sessionID, err := api.beginPasskeyCeremony(r.Context(), uuid.NullUUID{}, session)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "BeginPasskeyLogin: couldn't store passkey session in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, passkeyCeremony{SessionID: sessionID, Options: assertion})
+}
+
+// FinishPasskeyLogin verifies the authenticator's response to BeginPasskeyLogin and logs the user in,
+// issuing the same tokens as LoginUser. A passkey that verified the user is already two factors, so
+// users with two-factor authentication aren't asked for a TOTP code as well.
+func (api *API) FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		SessionID  uuid.UUID       `json:"session_id"`
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishPasskeyLogin: couldn't decode parameters", err)
+		return
+	}
+
+	session, err := api.finishPasskeyCeremony(r.Context(), params.SessionID, uuid.NullUUID{})
+	if err != nil {
+		if err == errPasskeyCeremony {
+			respondWithError(w, http.StatusUnauthorized, "FinishPasskeyLogin: invalid or expired passkey session", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FinishPasskeyLogin: couldn't get passkey session from DB", err)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(params.Credential)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "FinishPasskeyLogin: couldn't parse credential", err)
+		return
+	}
+
+	var user *passkeyUser
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user, err = getPasskeyUser(r.Context(), api.DB, userID)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// Checks the signature over the challenge, origin and RP ID, so an assertion made for another site is useless here
+	_, credential, err := api.passkeys.ValidatePasskeyLogin(findUser, session, parsed)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "FinishPasskeyLogin: couldn't verify passkey", err)
+		return
+	}
+
+	stored, ok := user.credential(credential.ID)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "FinishPasskeyLogin: couldn't verify passkey", nil)
+		return
+	}
+
+	if err := api.usePasskey(r.Context(), stored, credential); err != nil {
+		if err == errPasskeyCloned {
+			respondWithError(w, http.StatusUnauthorized, "FinishPasskeyLogin: passkey may have been cloned, and has been disabled", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "FinishPasskeyLogin: couldn't update passkey in DB", err)
+		return
+	}
+
+	api.completeLogin(w, r, "FinishPasskeyLogin", user.user)
+}
+
+// usePasskey records a passkey login, storing the authenticator's new signature counter.
+// A counter that didn't increase means another copy of the key has been used, so the passkey
+// is flagged and refused from then on, until the user deletes it and registers a new one.
+func (api *API) usePasskey(ctx context.Context, stored database.WebauthnCredential, credential *webauthn.Credential) error {
+	if stored.CloneWarning {
+		return errPasskeyCloned
+	}
+
+	if err := api.DB.UpdateWebAuthnCredentialUse(ctx, database.UpdateWebAuthnCredentialUseParams{
+		ID:           stored.ID,
+		SignCount:    int64(credential.Authenticator.SignCount),
+		CloneWarning: credential.Authenticator.CloneWarning,
+		BackupState:  credential.Flags.BackupState,
+	}); err != nil {
+		return fmt.Errorf("updating passkey: %v", err)
+	}
+
+	if credential.Authenticator.CloneWarning {
+		return errPasskeyCloned
+	}
+	return nil
+}
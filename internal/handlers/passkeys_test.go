@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const testPasskeyOrigin = "http://localhost:8080"
+
+// softAuthenticator is a software passkey, standing in for a browser and authenticator in tests.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// authData builds authenticator data with the user present and verified flags set.
+func (a *softAuthenticator) authData(rpID string, attested []byte) []byte {
+	const flagUserPresent, flagUserVerified, flagAttested = 0x01, 0x04, 0x40
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := byte(flagUserPresent | flagUserVerified)
+	if attested != nil {
+		flags |= flagAttested
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony, challenge, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return data
+}
+
+// create answers a registration challenge with a new credential, using "none" attestation.
+func (a *softAuthenticator) create(t *testing.T, rpID, origin string, userHandle []byte, challenge string) []byte {
+	t.Helper()
+	a.userHandle = userHandle
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("cbor.Marshal() error = %v", err)
+	}
+
+	attested := make([]byte, 16) // Zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(rpID, attested),
+	})
+	if err != nil {
+		t.Fatalf("cbor.Marshal() error = %v", err)
+	}
+
+	return a.response(t, map[string]any{
+		"clientDataJSON":    a.clientData(t, "webauthn.create", challenge, origin),
+		"attestationObject": attestationObject,
+	})
+}
+
+// get answers a login challenge, signing with the credential made by create.
+func (a *softAuthenticator) get(t *testing.T, rpID, origin, challenge string) []byte {
+	t.Helper()
+	a.signCount++
+
+	authData := a.authData(rpID, nil)
+	clientData := a.clientData(t, "webauthn.get", challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, sha256Sum(append(authData, clientDataHash[:]...)))
+	if err != nil {
+		t.Fatalf("ecdsa.SignASN1() error = %v", err)
+	}
+
+	return a.response(t, map[string]any{
+		"clientDataJSON":    clientData,
+		"authenticatorData": authData,
+		"signature":         signature,
+		"userHandle":        a.userHandle,
+	})
+}
+
+func (a *softAuthenticator) response(t *testing.T, fields map[string]any) []byte {
+	t.Helper()
+	response := map[string]string{}
+	for name, value := range fields {
+		response[name] = base64.RawURLEncoding.EncodeToString(value.([]byte))
+	}
+	data, err := json.Marshal(map[string]any{
+		"id":       base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return data
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// registerSoftPasskey registers a software authenticator for user, returning it and the stored credential.
+func registerSoftPasskey(t *testing.T, passkeys *webauthn.WebAuthn, user *passkeyUser) (*softAuthenticator, database.WebauthnCredential) {
+	t.Helper()
+	authenticator := newSoftAuthenticator(t)
+
+	_, session, err := passkeys.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(
+		authenticator.create(t, "localhost", testPasskeyOrigin, user.WebAuthnID(), session.Challenge))
+	if err != nil {
+		t.Fatalf("ParseCredentialCreationResponseBytes() error = %v", err)
+	}
+	credential, err := passkeys.CreateCredential(user, *session, parsed)
+	if err != nil {
+		t.Fatalf("CreateCredential() error = %v", err)
+	}
+
+	params := newWebAuthnCredentialParams(user.user.ID, credential)
+	return authenticator, database.WebauthnCredential{
+		ID:              uuid.New(),
+		CreatedAt:       time.Now(),
+		UserID:          params.UserID,
+		CredentialID:    params.CredentialID,
+		PublicKey:       params.PublicKey,
+		AttestationType: params.AttestationType,
+		Aaguid:          params.Aaguid,
+		Transports:      params.Transports,
+		SignCount:       params.SignCount,
+		BackupEligible:  params.BackupEligible,
+		BackupState:     params.BackupState,
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	passkeys, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Chirpy",
+		RPOrigins:     []string{testPasskeyOrigin},
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		rpID          string
+		origin        string
+		replay        bool
+		wantErr       bool
+		wantCloneWarn bool
+	}{
+		{name: "valid assertion", rpID: "localhost", origin: testPasskeyOrigin},
+		{name: "phishing origin", rpID: "localhost", origin: "http://chirpy.example.com", wantErr: true},
+		{name: "other relying party", rpID: "chirpy.example.com", origin: testPasskeyOrigin, wantErr: true},
+		{name: "replayed sign count", rpID: "localhost", origin: testPasskeyOrigin, replay: true, wantCloneWarn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &passkeyUser{user: database.User{ID: uuid.New(), Email: "user@example.com"}}
+			authenticator, stored := registerSoftPasskey(t, passkeys, user)
+			user.credentials = []database.WebauthnCredential{stored}
+			if tt.replay {
+				// Another copy of the key has already logged in with a later counter
+				user.credentials[0].SignCount = 5
+			}
+
+			_, session, err := passkeys.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+			if err != nil {
+				t.Fatalf("BeginDiscoverableLogin() error = %v", err)
+			}
+			parsed, err := protocol.ParseCredentialRequestResponseBytes(
+				authenticator.get(t, tt.rpID, tt.origin, session.Challenge))
+			if err != nil {
+				t.Fatalf("ParseCredentialRequestResponseBytes() error = %v", err)
+			}
+
+			findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+				if id, err := uuid.FromBytes(userHandle); err != nil || id != user.user.ID {
+					t.Errorf("user handle = %x, want %x", userHandle, user.user.ID[:])
+				}
+				return user, nil
+			}
+			_, credential, err := passkeys.ValidatePasskeyLogin(findUser, *session, parsed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePasskeyLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if credential.Authenticator.CloneWarning != tt.wantCloneWarn {
+				t.Errorf("CloneWarning = %v, want %v", credential.Authenticator.CloneWarning, tt.wantCloneWarn)
+			}
+			if _, ok := user.credential(credential.ID); !ok {
+				t.Errorf("credential(%x) not found", credential.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+)
+
+const passwordResetLifetime = 30 * time.Minute
+
+// ForgotPassword emails a password reset token to the user with the given email address.
+// It always responds 202 Accepted, whether or not there is such a user, and sends the email
+// in the background, so neither its response nor how long it takes reveals which email addresses have accounts.
+// Requests are throttled for each email address and IP address, whether or not the email is sent,
+// so no one can flood an inbox with reset emails.
+func (api *API) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "ForgotPassword: couldn't decode parameters", err)
+		return
+	}
+
+	wait, _, err := api.resets.reserve(r.Context(), params.Email, clientIP(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "ForgotPassword: couldn't check password reset requests", err)
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		respondWithError(w, http.StatusTooManyRequests, "ForgotPassword: too many password reset requests", nil)
+		return
+	}
+
+	go func(ctx context.Context) {
+		if err := api.sendPasswordResetEmail(ctx, params.Email); err != nil {
+			log.Printf("ForgotPassword: couldn't send password reset email: %v", err)
+		}
+	}(context.WithoutCancel(r.Context()))
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
+
+// sendPasswordResetEmail emails a reset token to the user with the given email address, if there is one.
+// Reset tokens are random like refresh tokens, and are only stored as digests in the same way.
+func (api *API) sendPasswordResetEmail(ctx context.Context, address string) error {
+	user, err := api.DB.GetUserByEmail(ctx, address)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("getting user: %v", err)
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return fmt.Errorf("making reset token: %v", err)
+	}
+
+	if err := api.DB.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: auth.HashRefreshToken(token),
+		ExpiresAt: time.Now().UTC().Add(passwordResetLifetime),
+	}); err != nil {
+		return fmt.Errorf("creating password reset: %v", err)
+	}
+
+	return api.mailer.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Reset your password by sending this token, with your new password, to POST /api/password/reset:\n\n" +
+			token + "\n\nThe token expires in 30 minutes. If you didn't ask to reset your password, you can ignore this email.",
+	})
+}
+
+// ResetPassword sets a new password for the user a reset token was sent to.
+// Every session is logged out, in case the account was taken over with the old password.
+func (api *API) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "ResetPassword: couldn't decode parameters", err)
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "ResetPassword: password can't be empty", nil)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "ResetPassword: couldn't hash password", err)
+		return
+	}
+
+	var reset database.PasswordReset
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		reset, err = q.UsePasswordReset(r.Context(), auth.HashRefreshToken(params.Token))
+		if err != nil {
+			return err
+		}
+
+		if err := q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             reset.UserID,
+			HashedPassword: hashedPassword,
+		}); err != nil {
+			return fmt.Errorf("updating password: %v", err)
+		}
+		// Any other reset tokens sent before this one can't be used to change it again
+		if err := q.ExpireUserPasswordResets(r.Context(), reset.UserID); err != nil {
+			return fmt.Errorf("expiring password resets: %v", err)
+		}
+		if err := q.RevokeUserRefreshTokens(r.Context(), reset.UserID); err != nil {
+			return fmt.Errorf("revoking refresh tokens: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "ResetPassword: reset token is invalid, expired or already used", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "ResetPassword: couldn't reset password in DB", err)
+		return
+	}
+
+	if err := api.revokeUserAccessTokens(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "ResetPassword: couldn't revoke access tokens", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
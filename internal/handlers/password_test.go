@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+	"github.com/google/uuid"
+)
+
+func TestForgotPassword(t *testing.T) {
+	api, mailer := newTestAPI(t)
+	user := createTestUser(t, api, true)
+
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail bool
+	}{
+		{"known email", user.Email, true},
+		{"unknown email", uuid.NewString() + "@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forgotPassword(t, api, tt.email, http.StatusAccepted)
+			if tt.wantEmail {
+				waitForEmail(t, mailer, tt.email)
+				return
+			}
+			// The email is sent in the background, so give it the time a real one would have had
+			time.Sleep(100 * time.Millisecond)
+			if sent := mailer.sentTo(tt.email); len(sent) != 0 {
+				t.Errorf("sent %d emails to an unknown address, want none", len(sent))
+			}
+		})
+	}
+}
+
+func TestForgotPasswordThrottled(t *testing.T) {
+	api, _ := newTestAPI(t)
+	address := uuid.NewString() + "@example.com"
+
+	for range passwordResetAccountPolicy.freeAttempts {
+		forgotPassword(t, api, address, http.StatusAccepted)
+	}
+	w := forgotPassword(t, api, address, http.StatusTooManyRequests)
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("throttled response has no Retry-After header")
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	api, mailer := newTestAPI(t)
+	authn := auth.NewMiddleware(api.keyring, api.denylist)
+	protected := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	const newPassword = "new password"
+
+	tests := []struct {
+		name string
+		// token returns a reset token for the user, which may have been used or have expired
+		token      func(t *testing.T, user database.User) string
+		wantStatus int
+	}{
+		{
+			name: "emailed token",
+			token: func(t *testing.T, user database.User) string {
+				forgotPassword(t, api, user.Email, http.StatusAccepted)
+				return emailedToken(t, waitForEmail(t, mailer, user.Email))
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "token already used",
+			token: func(t *testing.T, user database.User) string {
+				token := createTestPasswordReset(t, api, user, time.Now().Add(passwordResetLifetime))
+				resetPassword(t, api, token, "first new password", http.StatusNoContent)
+				return token
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, user database.User) string {
+				return createTestPasswordReset(t, api, user, time.Now().Add(-time.Minute))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown token",
+			token:      func(t *testing.T, user database.User) string { return "not-a-reset-token" },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, api, true)
+			loggedIn := loginTestUser(t, api, user)
+			token := tt.token(t, user)
+
+			resetPassword(t, api, token, newPassword, tt.wantStatus)
+			if tt.wantStatus != http.StatusNoContent {
+				return
+			}
+
+			// Every session is logged out
+			refreshLogin(t, api, loggedIn.RefreshToken, http.StatusUnauthorized)
+			if got := useAccessToken(protected, loggedIn.Token); got != http.StatusUnauthorized {
+				t.Errorf("access token after reset status = %d, want %d", got, http.StatusUnauthorized)
+			}
+
+			r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": newPassword})
+			r.RemoteAddr = testIP() + ":1234"
+			serve(t, api.LoginUser, r, http.StatusOK)
+		})
+	}
+}
+
+func forgotPassword(t *testing.T, api *API, address string, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/password/forgot", map[string]string{"email": address})
+	r.RemoteAddr = testIP() + ":1234"
+	return serve(t, api.ForgotPassword, r, wantStatus)
+}
+
+func resetPassword(t *testing.T, api *API, token string, password string, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/password/reset", map[string]string{"token": token, "password": password})
+	serve(t, api.ResetPassword, r, wantStatus)
+}
+
+// createTestPasswordReset stores a reset token for a user, as if it had been emailed to them.
+func createTestPasswordReset(t *testing.T, api *API, user database.User, expiresAt time.Time) string {
+	t.Helper()
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+	if err := api.DB.CreatePasswordReset(context.Background(), database.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: auth.HashRefreshToken(token),
+		ExpiresAt: expiresAt.UTC(),
+	}); err != nil {
+		t.Fatalf("CreatePasswordReset() error = %v", err)
+	}
+	return token
+}
+
+// waitForEmail waits for the first email to an address sent in the background.
+func waitForEmail(t *testing.T, mailer *testMailer, address string) email.Message {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if sent := mailer.sentTo(address); len(sent) > 0 {
+			return sent[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no email sent to %s", address)
+	return email.Message{}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (api *API) Rechirp(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Rechirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Rechirp: no authenticated user in request context", nil)
+		return
+	}
+
+	// A rechirp is a chirp of its own, so it needs a verified email address like any other
+	verified, err := api.emailVerified(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Rechirp: couldn't get user from DB", err)
+		return
+	}
+	if !verified {
+		respondWithError(w, http.StatusForbidden, "Rechirp: email address must be verified before rechirping", nil)
+		return
+	}
+
+	original, err := api.DB.GetChirpByID(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Rechirp: no chirps found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Rechirp: couldn't get chirps from DB", err)
+		return
+	}
+	if !visibleTo(original, uuid.NullUUID{UUID: userID, Valid: true}) {
+		respondWithError(w, http.StatusNotFound, "Rechirp: no chirps found for the given ID", nil)
+		return
+	}
+
+	// Rechirping a rechirp rechirps the chirp it points to
+	repostOfID := uuid.NullUUID{UUID: original.ID, Valid: true}
+	if original.RepostOfID.Valid {
+		repostOfID = original.RepostOfID
+	}
+
+	chirp, err := api.DB.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:     userID,
+		RepostOfID: repostOfID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusConflict, "Rechirp: chirp has already been rechirped by this user", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Rechirp: couldn't create rechirp in DB", err)
+		return
+	}
+
+	responses, err := api.toChirpResponses(r.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Rechirp: couldn't get original chirp from DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, responses[0])
+}
+
+func (api *API) UndoRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "UndoRechirp: couldn't parse path value 'chirpID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "UndoRechirp: no authenticated user in request context", nil)
+		return
+	}
+
+	deleted, err := api.DB.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:     userID,
+		RepostOfID: uuid.NullUUID{UUID: chirpUUID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UndoRechirp: couldn't delete rechirp from DB", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "UndoRechirp: no rechirp found for the given chirp ID", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// errSelfDemotion is returned when an admin tries to take away their own admin role,
+// which could leave no one able to manage roles.
+var errSelfDemotion = errors.New("admins can't remove their own admin role")
+
+// userRole is the response to a change of a user's role.
+type userRole struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetUserRole grants a role to a user. Only admins can reach it.
+func (api *API) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role   auth.Role `json:"role"`
+		Reason string    `json:"reason"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "SetUserRole: couldn't decode parameters", err)
+		return
+	}
+	if !params.Role.Valid() {
+		respondWithError(w, http.StatusBadRequest, "SetUserRole: role must be one of user, moderator or admin", nil)
+		return
+	}
+
+	api.changeUserRole(w, r, "SetUserRole", params.Role, params.Reason)
+}
+
+// RevokeUserRole takes away a user's role, leaving them a plain user. Only admins can reach it.
+func (api *API) RevokeUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "RevokeUserRole: couldn't decode parameters", err)
+		return
+	}
+
+	api.changeUserRole(w, r, "RevokeUserRole", auth.RoleUser, params.Reason)
+}
+
+func (api *API) changeUserRole(w http.ResponseWriter, r *http.Request, funcName string, role auth.Role, reason string) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, funcName+": couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	adminID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, funcName+": no authenticated user in request context", nil)
+		return
+	}
+
+	if userID == adminID && role != auth.RoleAdmin {
+		respondWithError(w, http.StatusBadRequest, funcName+": admins can't remove their own admin role", errSelfDemotion)
+		return
+	}
+
+	var user database.User
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		user, err = changeRole(r.Context(), q, userID, uuid.NullUUID{UUID: adminID, Valid: true}, role, reason)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, funcName+": no users found for the given ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, funcName+": couldn't change user role in DB", err)
+		return
+	}
+
+	// Access tokens carry the role they were issued with, so the user has to refresh to get one with the new role
+	if err := api.revokeUserAccessTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, funcName+": couldn't revoke access tokens", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, userRole{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		UpdatedAt: user.UpdatedAt,
+	})
+}
+
+// changeRole sets a user's role and records the change in the audit trail.
+// Setting the role a user already has changes nothing, and isn't recorded.
+// changedBy is null for changes not made by an admin, such as bootstrapping the first one.
+func changeRole(ctx context.Context, q *database.Queries, userID uuid.UUID, changedBy uuid.NullUUID, role auth.Role, reason string) (database.User, error) {
+	// Lock the user, so concurrent changes are recorded with the right old role
+	user, err := q.GetUserByIDForUpdate(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+	if user.Role == string(role) {
+		return user, nil
+	}
+
+	oldRole := user.Role
+	user, err = q.SetUserRole(ctx, database.SetUserRoleParams{
+		ID:   userID,
+		Role: string(role),
+	})
+	if err != nil {
+		return database.User{}, fmt.Errorf("setting user role: %v", err)
+	}
+
+	if _, err := q.CreateRoleChange(ctx, database.CreateRoleChangeParams{
+		UserID:    userID,
+		ChangedBy: changedBy,
+		OldRole:   oldRole,
+		NewRole:   user.Role,
+		Reason:    reason,
+	}); err != nil {
+		return database.User{}, fmt.Errorf("recording role change: %v", err)
+	}
+	return user, nil
+}
+
+// BootstrapAdmin makes the user with the given email an admin, so there is someone to grant other roles.
+func (api *API) BootstrapAdmin(ctx context.Context, email string) error {
+	user, err := api.DB.GetUserByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("getting user %v: %v", email, err)
+	}
+	return api.withTx(ctx, func(q *database.Queries) error {
+		_, err := changeRole(ctx, q, user.ID, uuid.NullUUID{}, auth.RoleAdmin, "bootstrap admin")
+		return err
+	})
+}
+
+// GetRoleChanges returns the audit trail of a user's role changes, oldest first.
+func (api *API) GetRoleChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetRoleChanges: couldn't parse path value 'userID' to UUID", err)
+		return
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetRoleChanges: invalid 'limit' query param", err)
+		return
+	}
+
+	cursor, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "GetRoleChanges: invalid 'cursor' query param", err)
+		return
+	}
+
+	type roleChangePage struct {
+		Changes    []database.RoleChange `json:"changes"`
+		NextCursor string                `json:"next_cursor,omitempty"`
+	}
+
+	// Fetch one more row than requested, to know whether there is a next page
+	changes, err := api.DB.GetRoleChangesPage(r.Context(), database.GetRoleChangesPageParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.createdAt(),
+		CursorID:        cursor.id(),
+		PageSize:        pageSize + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetRoleChanges: couldn't get role changes from DB", err)
+		return
+	}
+
+	page := roleChangePage{Changes: changes}
+	if len(changes) > int(pageSize) {
+		page.Changes = changes[:pageSize]
+		last := page.Changes[len(page.Changes)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	if page.Changes == nil {
+		page.Changes = []database.RoleChange{}
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+func TestChangeUserRoleRevokesAccessTokens(t *testing.T) {
+	api, _ := newTestAPI(t)
+	admin := createTestUser(t, api, true)
+	authn := auth.NewMiddleware(api.keyring, api.denylist)
+	protected := authn.Required(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		handler  func(api *API) http.HandlerFunc
+		method   string
+		body     any
+		wantRole auth.Role
+	}{
+		{
+			name:     "moderator demoted",
+			handler:  func(api *API) http.HandlerFunc { return api.RevokeUserRole },
+			method:   "DELETE",
+			wantRole: auth.RoleUser,
+		},
+		{
+			name:     "moderator promoted",
+			handler:  func(api *API) http.HandlerFunc { return api.SetUserRole },
+			method:   "PUT",
+			body:     map[string]string{"role": string(auth.RoleAdmin)},
+			wantRole: auth.RoleAdmin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, api, true)
+			if _, err := api.DB.SetUserRole(context.Background(), database.SetUserRoleParams{
+				ID:   user.ID,
+				Role: string(auth.RoleModerator),
+			}); err != nil {
+				t.Fatalf("SetUserRole() error = %v", err)
+			}
+
+			loggedIn := loginTestUser(t, api, user)
+			if got := useAccessToken(protected, loggedIn.Token); got != http.StatusNoContent {
+				t.Fatalf("access token before role change status = %d, want %d", got, http.StatusNoContent)
+			}
+
+			r := newTestRequest(t, tt.method, "/admin/users/"+user.ID.String()+"/role", tt.body)
+			r.SetPathValue("userID", user.ID.String())
+			w := serve(t, tt.handler(api), asUser(r, admin), http.StatusOK)
+			if strings.Contains(w.Body.String(), "hashed_password") {
+				t.Errorf("response includes the password hash: %s", w.Body)
+			}
+			if got := decodeResponse[userRole](t, w); got.Role != string(tt.wantRole) {
+				t.Errorf("response role = %q, want %q", got.Role, tt.wantRole)
+			}
+
+			if got := useAccessToken(protected, loggedIn.Token); got != http.StatusUnauthorized {
+				t.Errorf("access token after role change status = %d, want %d", got, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+// useAccessToken makes a request with an access token, returning the response status.
+func useAccessToken(handler http.Handler, token string) int {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// searchResult is a chirp matching a search, with how well it matched.
+type searchResult struct {
+	chirpResponse
+	Rank float32 `json:"rank"`
+}
+
+func (api *API) SearchChirps(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	authorID := r.URL.Query().Get("author_id")
+
+	tsQuery, err := buildTSQuery(q)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "SearchChirps: invalid 'q' query param", err)
+		return
+	}
+
+	var authorUUID uuid.NullUUID
+	if authorID != "" {
+		authorUUID.UUID, err = uuid.Parse(authorID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "SearchChirps: couldn't parse query param 'author_id' to UUID", err)
+			return
+		}
+		authorUUID.Valid = true
+	}
+
+	pageSize, err := parsePageSize(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "SearchChirps: invalid 'limit' query param", err)
+		return
+	}
+
+	viewerID := api.viewerID(r)
+	rows, err := api.DB.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:    tsQuery,
+		ViewerID: viewerID,
+		AuthorID: authorUUID,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "SearchChirps: couldn't search chirps in DB", err)
+		return
+	}
+
+	// Results carry the same likes and originals as every other list of chirps, along with how well they matched
+	matched := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		matched[i] = database.Chirp{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Body:          row.Body,
+			UserID:        row.UserID,
+			ParentChirpID: row.ParentChirpID,
+			DeletedAt:     row.DeletedAt,
+			RepostOfID:    row.RepostOfID,
+			QuoteOfID:     row.QuoteOfID,
+			HiddenAt:      row.HiddenAt,
+		}
+	}
+	chirps, err := api.toChirpResponses(r.Context(), matched, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "SearchChirps: couldn't get chirp likes from DB", err)
+		return
+	}
+
+	results := make([]searchResult, len(chirps))
+	for i, chirp := range chirps {
+		results[i] = searchResult{chirpResponse: chirp, Rank: rows[i].Rank}
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// buildTSQuery converts a user search string into Postgres to_tsquery syntax.
+// Words are ANDed together, "quoted words" become a phrase and a trailing * makes a prefix match.
+// Any other punctuation is dropped, so the result is always a valid tsquery.
+func buildTSQuery(q string) (string, error) {
+	var terms []string
+
+	for i, part := range strings.Split(q, `"`) {
+		// Odd parts sit between a pair of quotes
+		if i%2 == 1 {
+			var words []string
+			for _, word := range strings.Fields(part) {
+				if word = sanitiseSearchWord(word); word != "" {
+					words = append(words, word)
+				}
+			}
+			if len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			word = sanitiseSearchWord(word)
+			if word == "" {
+				continue
+			}
+			if prefix {
+				word += ":*"
+			}
+			terms = append(terms, word)
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query has no searchable words")
+	}
+
+	return strings.Join(terms, " & "), nil
+}
+
+func sanitiseSearchWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+}
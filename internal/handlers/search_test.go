@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Single word",
+			query:   "chirpy",
+			want:    "chirpy",
+			wantErr: false,
+		},
+		{
+			name:    "Multiple words",
+			query:   "Hello  World",
+			want:    "hello & world",
+			wantErr: false,
+		},
+		{
+			name:    "Phrase",
+			query:   `"good morning" birds`,
+			want:    "(good <-> morning) & birds",
+			wantErr: false,
+		},
+		{
+			name:    "Prefix",
+			query:   "chirp*",
+			want:    "chirp:*",
+			wantErr: false,
+		},
+		{
+			name:    "Punctuation is dropped",
+			query:   "it's & (fine) | !",
+			want:    "its & fine",
+			wantErr: false,
+		},
+		{
+			name:    "Unterminated quote",
+			query:   `"good morning`,
+			want:    "(good <-> morning)",
+			wantErr: false,
+		},
+		{
+			name:    "Empty",
+			query:   "  ",
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTSQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildTSQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("buildTSQuery() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchChirps(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+	viewer := createTestUser(t, api, true)
+	// A word no other test's chirps contain, so only these chirps match
+	word := "word" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	original, err := api.DB.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   "original " + word,
+		UserID: author.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	likeTestChirp(t, api, "POST", api.LikeChirp, viewer, original.ID)
+	quote, err := api.DB.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:      "quote " + word,
+		UserID:    author.ID,
+		QuoteOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+
+	r := newTestRequest(t, "GET", "/api/chirps/search?q="+word, nil)
+	results := decodeResponse[[]searchResult](t, serve(t, api.SearchChirps, asUser(r, viewer), http.StatusOK))
+	if len(results) != 2 {
+		t.Fatalf("SearchChirps() returned %d chirps, want 2", len(results))
+	}
+
+	for _, result := range results {
+		if result.Rank <= 0 {
+			t.Errorf("chirp %v rank = %v, want more than 0", result.ID, result.Rank)
+		}
+		switch result.ID {
+		case original.ID:
+			if result.LikeCount != 1 || likedByMe(result.chirpResponse) != true {
+				t.Errorf("original like_count = %d, liked_by_me = %v, want 1 and true", result.LikeCount, likedByMe(result.chirpResponse))
+			}
+		case quote.ID:
+			if likedByMe(result.chirpResponse) != false {
+				t.Errorf("quote liked_by_me = %v, want false", likedByMe(result.chirpResponse))
+			}
+			if result.Original == nil || result.Original.ID != original.ID || result.Original.Body != original.Body {
+				t.Errorf("quote original = %+v, want %v", result.Original, original.ID)
+			}
+		default:
+			t.Errorf("SearchChirps() returned chirp %v, which wasn't posted by this test", result.ID)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// session is a logged in device. Its ID is the family of refresh tokens issued to it,
+// which stays the same as the tokens are rotated.
+type session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// clientIP returns the IP address a request came from.
+// Forwarding headers are ignored, as they can be set by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// GetSessions lists the authenticated user's active sessions, most recently used first.
+func (api *API) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "GetSessions: no authenticated user in request context", nil)
+		return
+	}
+
+	rows, err := api.DB.GetActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "GetSessions: couldn't get sessions from DB", err)
+		return
+	}
+
+	sessions := make([]session, len(rows))
+	for i, row := range rows {
+		sessions[i] = session{
+			ID:         row.FamilyID,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession logs out one of the authenticated user's sessions.
+func (api *API) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "RevokeSession: couldn't parse path value 'sessionID' to UUID", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "RevokeSession: no authenticated user in request context", nil)
+		return
+	}
+
+	revoked, err := api.DB.RevokeSession(r.Context(), database.RevokeSessionParams{
+		UserID:   userID,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeSession: couldn't revoke session in DB", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "RevokeSession: no active sessions found for the given ID", nil)
+		return
+	}
+
+	if err := api.revokeSessionAccessTokens(r.Context(), sessionID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeSession: couldn't revoke access tokens", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// RevokeAllSessions logs the authenticated user out everywhere, revoking every refresh and access token they hold.
+func (api *API) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "RevokeAllSessions: no authenticated user in request context", nil)
+		return
+	}
+
+	if err := api.DB.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeAllSessions: couldn't revoke refresh tokens in DB", err)
+		return
+	}
+
+	if err := api.revokeUserAccessTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "RevokeAllSessions: couldn't revoke access tokens", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// revokeAccessToken denylists the access token with the given ID, issued at issuedAt,
+// until it would have expired anyway.
+func (api *API) revokeAccessToken(ctx context.Context, id string, issuedAt time.Time) error {
+	// Refresh tokens issued before access tokens had IDs have no record of them
+	if id == "" {
+		return nil
+	}
+
+	expiresAt := issuedAt.Add(accessTokenLifetime + auth.ClockSkewLeeway)
+	if time.Now().After(expiresAt) {
+		return nil
+	}
+	return api.denylist.Revoke(ctx, id, expiresAt)
+}
+
+// revokeSessionAccessTokens denylists the access tokens issued to a session that may still be valid.
+func (api *API) revokeSessionAccessTokens(ctx context.Context, familyID uuid.UUID) error {
+	tokens, err := api.DB.GetFamilyAccessTokens(ctx, database.GetFamilyAccessTokensParams{
+		FamilyID:  familyID,
+		CreatedAt: time.Now().UTC().Add(-(accessTokenLifetime + auth.ClockSkewLeeway)),
+	})
+	if err != nil {
+		return fmt.Errorf("getting access tokens of session: %v", err)
+	}
+
+	for _, token := range tokens {
+		if err := api.revokeAccessToken(ctx, token.AccessTokenID, token.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeUserAccessTokens denylists the access tokens issued to any of a user's sessions that may still be valid.
+func (api *API) revokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	tokens, err := api.DB.GetUserAccessTokens(ctx, database.GetUserAccessTokensParams{
+		UserID:    userID,
+		CreatedAt: time.Now().UTC().Add(-(accessTokenLifetime + auth.ClockSkewLeeway)),
+	})
+	if err != nil {
+		return fmt.Errorf("getting access tokens of user: %v", err)
+	}
+
+	for _, token := range tokens {
+		if err := api.revokeAccessToken(ctx, token.AccessTokenID, token.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// loginChallengeLifetime is how long a user has to send their second factor after their password
+	loginChallengeLifetime = 5 * time.Minute
+	recoveryCodeCount      = 10
+)
+
+var (
+	// errTwoFactorEnabled is returned when a user who already has two-factor authentication tries to enrol again.
+	errTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// errNoTOTPEnrolment is returned when a code is sent to confirm an enrolment that hasn't been started.
+	errNoTOTPEnrolment = errors.New("no two-factor enrolment to verify")
+	// errInvalidTOTPCode is returned when a TOTP code is wrong, or has already been used.
+	errInvalidTOTPCode = errors.New("invalid two-factor code")
+)
+
+// twoFactorEnabled reports whether a user has a confirmed TOTP authenticator.
+func (api *API) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	credential, err := api.DB.GetTOTPCredential(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return credential.ConfirmedAt.Valid, nil
+}
+
+// EnrollTwoFactor starts enrolling a TOTP authenticator for the authenticated user, returning the
+// otpauth URI to add it to their authenticator app. It isn't used to log in until VerifyTwoFactor confirms it.
+func (api *API) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		OTPAuthURI string `json:"otpauth_uri"`
+		Secret     string `json:"secret"`
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "EnrollTwoFactor: no authenticated user in request context", nil)
+		return
+	}
+
+	user, err := api.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "EnrollTwoFactor: couldn't get user from DB", err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "EnrollTwoFactor: couldn't generate secret", err)
+		return
+	}
+	encryptedSecret, err := api.totpCipher.Encrypt(secret)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "EnrollTwoFactor: couldn't encrypt secret", err)
+		return
+	}
+
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		credential, err := q.GetTOTPCredentialForUpdate(r.Context(), userID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("getting TOTP credential: %v", err)
+		}
+		if err == nil && credential.ConfirmedAt.Valid {
+			return errTwoFactorEnabled
+		}
+
+		// Enrolling again before verifying replaces the pending secret
+		if err := q.UpsertPendingTOTPCredential(r.Context(), database.UpsertPendingTOTPCredentialParams{
+			UserID: userID,
+			Secret: encryptedSecret,
+		}); err != nil {
+			return fmt.Errorf("storing TOTP credential: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errTwoFactorEnabled {
+			respondWithError(w, http.StatusConflict, "EnrollTwoFactor: two-factor authentication is already enabled", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "EnrollTwoFactor: couldn't store TOTP credential in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		OTPAuthURI: auth.TOTPURI(user.Email, secret),
+		Secret:     auth.EncodeTOTPSecret(secret),
+	})
+}
+
+// VerifyTwoFactor confirms a pending TOTP enrolment with a code from the authenticator, turning on
+// two-factor authentication. It returns the user's recovery codes, which are only ever shown this once.
+func (api *API) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "VerifyTwoFactor: couldn't decode parameters", err)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "VerifyTwoFactor: no authenticated user in request context", nil)
+		return
+	}
+
+	// Hashing is slow, so the codes are made before the credential is locked
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		code, err := auth.GenerateRecoveryCode()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "VerifyTwoFactor: couldn't generate recovery code", err)
+			return
+		}
+		hash, err := auth.HashPassword(code)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "VerifyTwoFactor: couldn't hash recovery code", err)
+			return
+		}
+		recoveryCodes[i], recoveryCodeHashes[i] = code, hash
+	}
+
+	err := api.withTx(r.Context(), func(q *database.Queries) error {
+		credential, err := q.GetTOTPCredentialForUpdate(r.Context(), userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errNoTOTPEnrolment
+			}
+			return fmt.Errorf("getting TOTP credential: %v", err)
+		}
+		if credential.ConfirmedAt.Valid {
+			return errTwoFactorEnabled
+		}
+
+		counter, err := api.checkTOTP(credential, params.Code)
+		if err != nil {
+			return err
+		}
+
+		if err := q.ConfirmTOTPCredential(r.Context(), database.ConfirmTOTPCredentialParams{
+			UserID:          userID,
+			LastUsedCounter: counter,
+		}); err != nil {
+			return fmt.Errorf("confirming TOTP credential: %v", err)
+		}
+		if err := q.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+			return fmt.Errorf("deleting old recovery codes: %v", err)
+		}
+		for _, hash := range recoveryCodeHashes {
+			if err := q.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: hash,
+			}); err != nil {
+				return fmt.Errorf("creating recovery code: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errNoTOTPEnrolment {
+			respondWithError(w, http.StatusBadRequest, "VerifyTwoFactor: no two-factor enrolment to verify", err)
+			return
+		}
+		if err == errTwoFactorEnabled {
+			respondWithError(w, http.StatusConflict, "VerifyTwoFactor: two-factor authentication is already enabled", err)
+			return
+		}
+		if err == errInvalidTOTPCode {
+			respondWithError(w, http.StatusBadRequest, "VerifyTwoFactor: invalid two-factor code", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "VerifyTwoFactor: couldn't enable two-factor authentication in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: recoveryCodes})
+}
+
+// LoginTwoFactor completes a login started by LoginUser, given the challenge token it returned,
+// and either a code from the user's authenticator or one of their recovery codes.
+func (api *API) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "LoginTwoFactor: couldn't decode parameters", err)
+		return
+	}
+
+	claims, err := auth.ParseJWTFor(params.ChallengeToken, api.keyring, auth.LoginChallengeAudience)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "LoginTwoFactor: invalid or expired challenge token", err)
+		return
+	}
+
+	user, err := api.DB.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "LoginTwoFactor: invalid or expired challenge token", err)
+		return
+	}
+
+	// Guessing codes counts against the account like guessing passwords
+	ip := clientIP(r)
+	if api.loginThrottled(w, r, "LoginTwoFactor", user.Email, ip) {
+		return
+	}
+
+	if params.RecoveryCode != "" {
+		err = api.useRecoveryCode(r.Context(), user.ID, params.RecoveryCode)
+	} else {
+		err = api.withTx(r.Context(), func(q *database.Queries) error {
+			credential, err := q.GetTOTPCredentialForUpdate(r.Context(), user.ID)
+			if err != nil {
+				return fmt.Errorf("getting TOTP credential: %v", err)
+			}
+			counter, err := api.checkTOTP(credential, params.Code)
+			if err != nil {
+				return err
+			}
+			// Only codes after this one are accepted from now on
+			return q.SetTOTPLastUsedCounter(r.Context(), database.SetTOTPLastUsedCounterParams{
+				UserID:          user.ID,
+				LastUsedCounter: counter,
+			})
+		})
+	}
+	if err != nil {
+		if err == errInvalidTOTPCode {
+			wait, recordErr := api.logins.failed(r.Context(), user.Email, ip)
+			if recordErr != nil {
+				respondWithError(w, http.StatusInternalServerError, "LoginTwoFactor: couldn't get login attempts", recordErr)
+				return
+			}
+			if wait > 0 {
+				setRetryAfter(w, wait)
+			}
+			respondWithError(w, http.StatusUnauthorized, "LoginTwoFactor: invalid two-factor code", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "LoginTwoFactor: couldn't check two-factor code", err)
+		return
+	}
+	if err := api.logins.passed(r.Context(), ip); err != nil {
+		log.Printf("LoginTwoFactor: %v", err)
+	}
+
+	api.completeLogin(w, r, "LoginTwoFactor", user)
+}
+
+// checkTOTP checks a code against a TOTP credential, returning the counter it matched.
+// Codes at or before the last one used are rejected, so a code seen by someone else can't be replayed.
+func (api *API) checkTOTP(credential database.TotpCredential, code string) (int64, error) {
+	secret, err := api.totpCipher.Decrypt(credential.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypting TOTP secret: %v", err)
+	}
+
+	counter, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok || counter <= credential.LastUsedCounter {
+		return 0, errInvalidTOTPCode
+	}
+	return counter, nil
+}
+
+// useRecoveryCode marks one of a user's recovery codes as used, if code matches one that hasn't been.
+func (api *API) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	codes, err := api.DB.GetUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("getting recovery codes: %v", err)
+	}
+
+	code = auth.NormaliseRecoveryCode(code)
+	for _, recoveryCode := range codes {
+		match, err := auth.CheckPasswordHash(code, recoveryCode.CodeHash)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
+
+		// Two logins racing with the same code can't both use it
+		used, err := api.DB.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			return fmt.Errorf("using recovery code: %v", err)
+		}
+		if used == 0 {
+			return errInvalidTOTPCode
+		}
+		return nil
+	}
+	return errInvalidTOTPCode
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+)
+
+func TestLoginUserTwoFactorChallenge(t *testing.T) {
+	api, _ := newTestAPI(t)
+	user := createTestUser(t, api, true)
+	enrollTestTwoFactor(t, api, user)
+
+	r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": testPassword})
+	r.RemoteAddr = testIP() + ":1234"
+	got := decodeResponse[map[string]any](t, serve(t, api.LoginUser, r, http.StatusOK))
+	if got["two_factor_required"] != true || got["challenge_token"] == "" {
+		t.Errorf("LoginUser() = %v, want a two-factor challenge", got)
+	}
+	for _, key := range []string{"token", "refresh_token"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("LoginUser() returned %s before the second factor", key)
+		}
+	}
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	type attempt struct {
+		code         string
+		recoveryCode string
+		wantStatus   int
+	}
+	tests := []struct {
+		name string
+		// attempts returns the codes sent for one challenge, in order, given the user's secret and recovery codes,
+		// and the counter of the code that verified the enrolment
+		attempts func(secret []byte, recoveryCodes []string, verified int64) []attempt
+	}{
+		{
+			name: "authenticator code",
+			attempts: func(secret []byte, recoveryCodes []string, verified int64) []attempt {
+				return []attempt{{code: auth.TOTPCode(secret, verified+1), wantStatus: http.StatusOK}}
+			},
+		},
+		{
+			name: "replayed authenticator code",
+			attempts: func(secret []byte, recoveryCodes []string, verified int64) []attempt {
+				code := auth.TOTPCode(secret, verified+1)
+				return []attempt{
+					{code: code, wantStatus: http.StatusOK},
+					{code: code, wantStatus: http.StatusUnauthorized},
+				}
+			},
+		},
+		{
+			name: "code used to verify the enrolment",
+			attempts: func(secret []byte, recoveryCodes []string, verified int64) []attempt {
+				return []attempt{{code: auth.TOTPCode(secret, verified), wantStatus: http.StatusUnauthorized}}
+			},
+		},
+		{
+			name: "code outside the allowed clock skew",
+			attempts: func(secret []byte, recoveryCodes []string, verified int64) []attempt {
+				return []attempt{{code: auth.TOTPCode(secret, verified+5), wantStatus: http.StatusUnauthorized}}
+			},
+		},
+		{
+			name: "recovery code",
+			attempts: func(secret []byte, recoveryCodes []string, verified int64) []attempt {
+				return []attempt{
+					{recoveryCode: recoveryCodes[0], wantStatus: http.StatusOK},
+					{recoveryCode: recoveryCodes[0], wantStatus: http.StatusUnauthorized},
+					{recoveryCode: recoveryCodes[1], wantStatus: http.StatusOK},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, api, true)
+			secret, recoveryCodes := enrollTestTwoFactor(t, api, user)
+			ip := testIP()
+			challengeToken := challengeTestUser(t, api, user, ip)
+
+			for _, a := range tt.attempts(secret, recoveryCodes, verifiedCounter(t, api, user)) {
+				w := loginTwoFactor(t, api, challengeToken, a.code, a.recoveryCode, ip, a.wantStatus)
+				if a.wantStatus != http.StatusOK {
+					continue
+				}
+				if got := decodeResponse[loggedInUser](t, w); got.ID != user.ID || got.Token == "" || got.RefreshToken == "" {
+					t.Errorf("LoginTwoFactor() = %+v, want tokens for %v", got, user.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginTwoFactorThrottled(t *testing.T) {
+	api, _ := newTestAPI(t)
+	user := createTestUser(t, api, true)
+	secret, _ := enrollTestTwoFactor(t, api, user)
+	ip := testIP()
+	challengeToken := challengeTestUser(t, api, user, ip)
+	verified := verifiedCounter(t, api, user)
+	wrongCode := auth.TOTPCode(secret, verified+5)
+
+	// Guessing codes counts against the account, like guessing its password
+	for failures := accountFailures(t, api, user); failures < int32(accountLoginPolicy.freeAttempts); failures++ {
+		loginTwoFactor(t, api, challengeToken, wrongCode, "", ip, http.StatusUnauthorized)
+		if got := accountFailures(t, api, user); got != failures+1 {
+			t.Fatalf("account failures after a wrong code = %d, want %d", got, failures+1)
+		}
+	}
+
+	// Once the account must wait, even the right code is turned away
+	w := loginTwoFactor(t, api, challengeToken, auth.TOTPCode(secret, verified+1), "", ip, http.StatusUnauthorized)
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("throttled response has no Retry-After header")
+	}
+}
+
+// enrollTestTwoFactor enrols and verifies an authenticator for a user, with the code for the current period.
+// It returns the authenticator's secret, and the user's recovery codes.
+func enrollTestTwoFactor(t *testing.T, api *API, user database.User) ([]byte, []string) {
+	t.Helper()
+	type enrolment struct {
+		Secret string `json:"secret"`
+	}
+	type verification struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	r := newTestRequest(t, "POST", "/api/users/2fa/enroll", nil)
+	enrolled := decodeResponse[enrolment](t, serve(t, api.EnrollTwoFactor, asUser(r, user), http.StatusOK))
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrolled.Secret)
+	if err != nil {
+		t.Fatalf("decoding TOTP secret: %v", err)
+	}
+
+	r = newTestRequest(t, "POST", "/api/users/2fa/verify", map[string]string{"code": auth.TOTPCode(secret, auth.TOTPCounter(time.Now()))})
+	verified := decodeResponse[verification](t, serve(t, api.VerifyTwoFactor, asUser(r, user), http.StatusOK))
+	if len(verified.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("VerifyTwoFactor() returned %d recovery codes, want %d", len(verified.RecoveryCodes), recoveryCodeCount)
+	}
+	return secret, verified.RecoveryCodes
+}
+
+// challengeTestUser logs in a user with two-factor authentication with their password, returning their challenge token.
+func challengeTestUser(t *testing.T, api *API, user database.User, ip string) string {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/login", map[string]string{"email": user.Email, "password": testPassword})
+	r.RemoteAddr = ip + ":1234"
+	return decodeResponse[twoFactorChallenge](t, serve(t, api.LoginUser, r, http.StatusOK)).ChallengeToken
+}
+
+func loginTwoFactor(t *testing.T, api *API, challengeToken string, code string, recoveryCode string, ip string, wantStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/login/2fa", map[string]string{
+		"challenge_token": challengeToken,
+		"code":            code,
+		"recovery_code":   recoveryCode,
+	})
+	r.RemoteAddr = ip + ":1234"
+	return serve(t, api.LoginTwoFactor, r, wantStatus)
+}
+
+// verifiedCounter returns the counter of the last code a user's authenticator was used with.
+func verifiedCounter(t *testing.T, api *API, user database.User) int64 {
+	t.Helper()
+	credential, err := api.DB.GetTOTPCredential(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetTOTPCredential() error = %v", err)
+	}
+	return credential.LastUsedCounter
+}
+
+func accountFailures(t *testing.T, api *API, user database.User) int32 {
+	t.Helper()
+	attempts, err := api.DB.GetLoginAttempts(context.Background(), database.GetLoginAttemptsParams{
+		Kind:    loginAttemptAccount,
+		Subject: normaliseLoginEmail(user.Email),
+	})
+	if err != nil {
+		t.Fatalf("GetLoginAttempts() error = %v", err)
+	}
+	return attempts.Failures
+}
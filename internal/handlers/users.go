@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/corygyarmathy/chirpy/internal/auth"
@@ -20,6 +22,11 @@ func (api *API) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateEmail(params.Email); err != nil {
+		respondWithError(w, http.StatusBadRequest, "CreateUser: invalid email address", err)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "CreateUser: couldn't hash password", err)
@@ -35,6 +42,11 @@ func (api *API) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account is still created if the email can't be sent, as a new one is sent if the email is updated
+	if err := api.sendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("CreateUser: couldn't send verification email: %v", err)
+	}
+
 	respondWithJSON(w, http.StatusCreated, user)
 }
 
@@ -50,15 +62,14 @@ func (api *API) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessTokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "UpdateUser: failed to get access token from request header", err)
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "UpdateUser: no authenticated user in request context", nil)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessTokenString, api.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "UpdateUser: user JWT not authorised", err)
+	if err := validateEmail(params.Email); err != nil {
+		respondWithError(w, http.StatusBadRequest, "UpdateUser: invalid email address", err)
 		return
 	}
 
@@ -68,15 +79,55 @@ func (api *API) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := api.DB.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		Email:          params.Email,
-		HashedPassword: hashedPassword,
+	var user database.User
+	var passwordChanged, emailChanged bool
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		current, err := q.GetUserByIDForUpdate(r.Context(), userID)
+		if err != nil {
+			return fmt.Errorf("getting user: %v", err)
+		}
+		samePassword, err := auth.CheckPasswordHash(params.Password, current.HashedPassword)
+		if err != nil {
+			return fmt.Errorf("checking current password: %v", err)
+		}
+		passwordChanged = !samePassword
+		emailChanged = current.Email != params.Email
+
+		user, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+			ID:             userID,
+			Email:          params.Email,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return fmt.Errorf("updating user: %v", err)
+		}
+
+		// A new password logs out every session, in case the old one was compromised
+		if passwordChanged {
+			if err := q.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+				return fmt.Errorf("revoking refresh tokens: %v", err)
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "UpdateUser: couldn't update user in DB", err)
 		return
 	}
 
+	if passwordChanged {
+		if err := api.revokeUserAccessTokens(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "UpdateUser: couldn't revoke access tokens", err)
+			return
+		}
+	}
+
+	// A new email address has to be verified again
+	if emailChanged {
+		if err := api.sendVerificationEmail(r.Context(), user); err != nil {
+			log.Printf("UpdateUser: couldn't send verification email: %v", err)
+		}
+	}
+
 	respondWithJSON(w, http.StatusOK, user)
 }
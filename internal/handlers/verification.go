@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+	"github.com/google/uuid"
+)
+
+const emailVerificationLifetime = 24 * time.Hour
+
+// errEmailChanged is returned when a verification token is for an email address the user no longer has.
+var errEmailChanged = errors.New("email address has changed since the verification token was sent")
+
+// validateEmail checks email is a bare address, like "user@example.com", without a display name.
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return err
+	}
+	if address.Address != email {
+		return fmt.Errorf("%q is not a bare email address", email)
+	}
+	return nil
+}
+
+// sendVerificationEmail emails the user a token that verifies their current email address.
+// The token is signed, so it can't be forged, and is recorded, so it can only be used once.
+func (api *API) sendVerificationEmail(ctx context.Context, user database.User) error {
+	verification, err := api.DB.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationLifetime),
+	})
+	if err != nil {
+		return fmt.Errorf("creating email verification: %v", err)
+	}
+
+	token, err := auth.MakeJWT(api.keyring, auth.TokenOptions{
+		UserID:    user.ID,
+		ExpiresIn: emailVerificationLifetime,
+		Audience:  []string{auth.EmailVerificationAudience},
+		ID:        verification.ID.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("making verification token: %v", err)
+	}
+
+	return api.mailer.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Verify your email address for Chirpy",
+		Body: "Verify your email address by sending this token to POST /api/users/verify:\n\n" +
+			token + "\n\nThe token expires in 24 hours. If you didn't sign up for Chirpy, you can ignore this email.",
+	})
+}
+
+// VerifyEmail marks a user's email address as verified, given the token emailed to them.
+func (api *API) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "VerifyEmail: couldn't decode parameters", err)
+		return
+	}
+
+	claims, err := auth.ParseJWTFor(params.Token, api.keyring, auth.EmailVerificationAudience)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "VerifyEmail: invalid or expired verification token", err)
+		return
+	}
+	verificationID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "VerifyEmail: invalid or expired verification token", err)
+		return
+	}
+
+	err = api.withTx(r.Context(), func(q *database.Queries) error {
+		verification, err := q.UseEmailVerification(r.Context(), verificationID)
+		if err != nil {
+			return err
+		}
+		if verification.UserID != claims.UserID {
+			return sql.ErrNoRows
+		}
+
+		verified, err := q.SetEmailVerified(r.Context(), database.SetEmailVerifiedParams{
+			ID:    verification.UserID,
+			Email: verification.Email,
+		})
+		if err != nil {
+			return fmt.Errorf("setting email verified: %v", err)
+		}
+		if verified == 0 {
+			return errEmailChanged
+		}
+		return nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "VerifyEmail: verification token has already been used or has expired", err)
+			return
+		}
+		if err == errEmailChanged {
+			respondWithError(w, http.StatusBadRequest, "VerifyEmail: email address has changed since the token was sent", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "VerifyEmail: couldn't verify email in DB", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// emailVerified reports whether a user can post chirps: they have verified their email address,
+// or verification isn't required.
+func (api *API) emailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if !api.requireVerifiedEmail {
+		return true, nil
+	}
+
+	user, err := api.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt.Valid, nil
+}
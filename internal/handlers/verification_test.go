@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
+	"github.com/google/uuid"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		email   string
+		wantErr bool
+	}{
+		{"user@example.com", false},
+		{"first.last+chirpy@example.co.uk", false},
+		{"", true},
+		{"not an email", true},
+		{"user@", true},
+		{"User <user@example.com>", true},
+		{" user@example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if err := validateEmail(tt.email); (err != nil) != tt.wantErr {
+				t.Errorf("validateEmail(%q) error = %v, wantErr %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostingRequiresVerifiedEmail(t *testing.T) {
+	api, _ := newTestAPI(t)
+	author := createTestUser(t, api, true)
+
+	tests := []struct {
+		name       string
+		verified   bool
+		post       func(t *testing.T, user database.User, wantStatus int)
+		wantStatus int
+	}{
+		{"chirp, verified", true, postTestChirp(api), http.StatusCreated},
+		{"chirp, unverified", false, postTestChirp(api), http.StatusForbidden},
+		{"rechirp, verified", true, postTestRechirp(api, author), http.StatusCreated},
+		{"rechirp, unverified", false, postTestRechirp(api, author), http.StatusForbidden},
+		{"edit, verified", true, postTestEdit(api), http.StatusOK},
+		{"edit, unverified", false, postTestEdit(api), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.post(t, createTestUser(t, api, tt.verified), tt.wantStatus)
+		})
+	}
+}
+
+func postTestChirp(api *API) func(t *testing.T, user database.User, wantStatus int) {
+	return func(t *testing.T, user database.User, wantStatus int) {
+		r := newTestRequest(t, "POST", "/api/chirps", map[string]string{"body": "hello"})
+		serve(t, api.CreateChirp, asUser(r, user), wantStatus)
+	}
+}
+
+func postTestRechirp(api *API, author database.User) func(t *testing.T, user database.User, wantStatus int) {
+	return func(t *testing.T, user database.User, wantStatus int) {
+		chirp := createTestChirp(t, api, author)
+		r := newTestRequest(t, "POST", "/api/chirps/"+chirp.ID.String()+"/rechirp", nil)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		serve(t, api.Rechirp, asUser(r, user), wantStatus)
+	}
+}
+
+// postTestEdit edits one of the user's chirps, as if they posted it before changing to an unverified email address.
+func postTestEdit(api *API) func(t *testing.T, user database.User, wantStatus int) {
+	return func(t *testing.T, user database.User, wantStatus int) {
+		chirp := createTestChirp(t, api, user)
+		r := newTestRequest(t, "PUT", "/api/chirps/"+chirp.ID.String(), map[string]string{"body": "edited"})
+		r.SetPathValue("chirpID", chirp.ID.String())
+		serve(t, api.UpdateChirp, asUser(r, user), wantStatus)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	api, mailer := newTestAPI(t)
+
+	tests := []struct {
+		name string
+		// before runs after the token is sent, and before it is used
+		before       func(t *testing.T, user database.User, token string)
+		wantStatus   int
+		wantVerified bool
+	}{
+		{
+			name:         "valid token",
+			before:       func(t *testing.T, user database.User, token string) {},
+			wantStatus:   http.StatusNoContent,
+			wantVerified: true,
+		},
+		{
+			name: "token already used",
+			before: func(t *testing.T, user database.User, token string) {
+				verifyEmail(t, api, token, http.StatusNoContent)
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantVerified: true,
+		},
+		{
+			name: "email changed since the token was sent",
+			before: func(t *testing.T, user database.User, token string) {
+				if _, err := api.DB.UpdateUser(context.Background(), database.UpdateUserParams{
+					ID:             user.ID,
+					Email:          uuid.NewString() + "@example.com",
+					HashedPassword: user.HashedPassword,
+				}); err != nil {
+					t.Fatalf("UpdateUser() error = %v", err)
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, api, false)
+			if err := api.sendVerificationEmail(context.Background(), user); err != nil {
+				t.Fatalf("sendVerificationEmail() error = %v", err)
+			}
+			sent := mailer.sentTo(user.Email)
+			if len(sent) != 1 {
+				t.Fatalf("sent %d verification emails, want 1", len(sent))
+			}
+			token := emailedToken(t, sent[0])
+
+			tt.before(t, user, token)
+			verifyEmail(t, api, token, tt.wantStatus)
+
+			got, err := api.DB.GetUserByID(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("GetUserByID() error = %v", err)
+			}
+			if got.EmailVerifiedAt.Valid != tt.wantVerified {
+				t.Errorf("email verified = %v, want %v", got.EmailVerifiedAt.Valid, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func verifyEmail(t *testing.T, api *API, token string, wantStatus int) {
+	t.Helper()
+	r := newTestRequest(t, "POST", "/api/users/verify", map[string]string{"token": token})
+	serve(t, api.VerifyEmail, r, wantStatus)
+}
+
+// emailedToken returns the token in an email, which is on a line of its own after the first paragraph.
+func emailedToken(t *testing.T, msg email.Message) string {
+	t.Helper()
+	paragraphs := strings.Split(msg.Body, "\n\n")
+	if len(paragraphs) < 2 {
+		t.Fatalf("no token in email body %q", msg.Body)
+	}
+	return paragraphs[1]
+}
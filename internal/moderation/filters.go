@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// WordFilter matches whole words against a word list, after Unicode normalisation.
+// Punctuation around a word doesn't stop it matching, e.g. "Kerfuffle!".
+type WordFilter struct {
+	Words  *WordList
+	Action Action
+}
+
+func (f *WordFilter) Check(text string) Verdict {
+	return checkWords(text, f.Words, f.Action, nil, "word list")
+}
+
+func (f *WordFilter) Reload(ctx context.Context) error {
+	return f.Words.Reload(ctx)
+}
+
+// LeetspeakFilter matches whole words against a word list after folding leetspeak
+// substitutions, e.g. "f0rn4x" matches "fornax".
+type LeetspeakFilter struct {
+	Words  *WordList
+	Action Action
+}
+
+// NewLeetspeakFilter creates a LeetspeakFilter, with its own copy of the word list folded for leetspeak.
+func NewLeetspeakFilter(source Source, action Action) *LeetspeakFilter {
+	words := NewWordList(source)
+	words.fold = foldLeetspeak
+	return &LeetspeakFilter{Words: words, Action: action}
+}
+
+func (f *LeetspeakFilter) Check(text string) Verdict {
+	return checkWords(text, f.Words, f.Action, foldLeetspeak, "leetspeak")
+}
+
+func (f *LeetspeakFilter) Reload(ctx context.Context) error {
+	return f.Words.Reload(ctx)
+}
+
+func checkWords(text string, words *WordList, action Action, fold func(rune) rune, reason string) Verdict {
+	n := normalise(text, fold)
+
+	var spans [][2]int
+	for _, w := range n.words() {
+		if words.contains(n.text[w[0]:w[1]]) {
+			start, end := n.span(w[0], w[1])
+			spans = append(spans, [2]int{start, end})
+		}
+	}
+
+	return verdictForSpans(text, spans, action, reason)
+}
+
+// RegexFilter matches regular expressions against the Unicode-normalised, lowercased text.
+type RegexFilter struct {
+	Patterns *PatternList
+	Action   Action
+}
+
+func (f *RegexFilter) Check(text string) Verdict {
+	n := normalise(text, nil)
+
+	var spans [][2]int
+	for _, p := range f.Patterns.all() {
+		for _, m := range p.FindAllStringIndex(n.text, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			start, end := n.span(m[0], m[1])
+			spans = append(spans, [2]int{start, end})
+		}
+	}
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+
+	return verdictForSpans(text, spans, f.Action, "pattern")
+}
+
+func (f *RegexFilter) Reload(ctx context.Context) error {
+	return f.Patterns.Reload(ctx)
+}
+
+var urlPattern = regexp.MustCompile(`(?:https?://)?((?:[\p{L}\p{N}-]+\.)+\p{L}{2,})(?:[:/?#][^\s]*)?`)
+
+// URLFilter matches links to blocked domains, including their subdomains.
+type URLFilter struct {
+	Domains *WordList
+	Action  Action
+}
+
+func (f *URLFilter) Check(text string) Verdict {
+	n := normalise(text, nil)
+
+	var spans [][2]int
+	for _, m := range urlPattern.FindAllStringSubmatchIndex(n.text, -1) {
+		host := n.text[m[2]:m[3]]
+		if f.isBlocked(host) {
+			start, end := n.span(m[0], m[1])
+			spans = append(spans, [2]int{start, end})
+		}
+	}
+
+	return verdictForSpans(text, spans, f.Action, "blocked URL")
+}
+
+func (f *URLFilter) isBlocked(host string) bool {
+	host = strings.TrimPrefix(host, "www.")
+	for {
+		if f.Domains.contains(host) {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return false
+		}
+		host = parent
+	}
+}
+
+func (f *URLFilter) Reload(ctx context.Context) error {
+	return f.Domains.Reload(ctx)
+}
+
+func verdictForSpans(text string, spans [][2]int, action Action, reason string) Verdict {
+	if len(spans) == 0 {
+		return Verdict{Action: Allow, Text: text}
+	}
+	if action == Censor {
+		text = censorSpans(text, spans)
+	}
+	return Verdict{Action: action, Text: text, Reason: reason}
+}
@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Source loads the terms for a list, e.g. from a file or the database.
+type Source interface {
+	Load(ctx context.Context) ([]string, error)
+}
+
+// SourceFunc adapts a function to a Source.
+type SourceFunc func(ctx context.Context) ([]string, error)
+
+func (f SourceFunc) Load(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticSource is a fixed list of terms.
+type StaticSource []string
+
+func (s StaticSource) Load(ctx context.Context) ([]string, error) {
+	return s, nil
+}
+
+// FileSource loads terms from a file, one per line. Blank lines and lines starting with '#' are ignored.
+type FileSource string
+
+func (path FileSource) Load(ctx context.Context) ([]string, error) {
+	f, err := os.Open(string(path))
+	if err != nil {
+		return nil, fmt.Errorf("opening term file: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var terms []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading term file: %v", err)
+	}
+	return terms, nil
+}
+
+// WordList is a set of normalised words loaded from a Source.
+// It is safe for concurrent use, and can be reloaded while in use.
+type WordList struct {
+	source Source
+	fold   func(rune) rune
+
+	mu    sync.RWMutex
+	words map[string]bool
+}
+
+func NewWordList(source Source) *WordList {
+	return &WordList{source: source}
+}
+
+func (l *WordList) Reload(ctx context.Context) error {
+	terms, err := l.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("loading word list: %v", err)
+	}
+
+	words := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		words[normalise(term, l.fold).text] = true
+	}
+
+	l.mu.Lock()
+	l.words = words
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *WordList) contains(word string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.words[word]
+}
+
+// PatternList is a set of regular expressions loaded from a Source.
+// It is safe for concurrent use, and can be reloaded while in use.
+type PatternList struct {
+	source Source
+
+	mu       sync.RWMutex
+	patterns []*regexp.Regexp
+}
+
+func NewPatternList(source Source) *PatternList {
+	return &PatternList{source: source}
+}
+
+func (l *PatternList) Reload(ctx context.Context) error {
+	terms, err := l.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("loading pattern list: %v", err)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(terms))
+	for _, term := range terms {
+		p, err := regexp.Compile(term)
+		if err != nil {
+			return fmt.Errorf("compiling pattern %q: %v", term, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	l.mu.Lock()
+	l.patterns = patterns
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *PatternList) all() []*regexp.Regexp {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.patterns
+}
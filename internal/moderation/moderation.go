@@ -0,0 +1,97 @@
+// Package moderation checks chirp content against a configurable chain of filters
+package moderation
+
+import (
+	"context"
+	"errors"
+)
+
+// Action is what a filter decided should happen to a piece of text.
+// Actions are ordered by severity, so the most severe one wins when filters disagree.
+type Action int
+
+const (
+	Allow Action = iota
+	Censor
+	Flag
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Censor:
+		return "censor"
+	case Flag:
+		return "flag"
+	case Reject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
+// Verdict is the outcome of a single filter.
+type Verdict struct {
+	Action Action
+	// Text is the checked text, with anything the filter censored replaced
+	Text   string
+	Reason string
+}
+
+type Filter interface {
+	Check(text string) Verdict
+}
+
+// Reloader is implemented by filters whose terms can be reloaded at runtime.
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// Result is the combined outcome of running text through a Pipeline.
+type Result struct {
+	Action  Action
+	Text    string
+	Reasons []string
+}
+
+// Pipeline runs text through a chain of filters in order.
+// Censored text is passed on to the next filter, and a Reject stops the chain.
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+func (p *Pipeline) Check(text string) Result {
+	result := Result{Action: Allow, Text: text}
+	for _, f := range p.filters {
+		verdict := f.Check(result.Text)
+		if verdict.Action == Allow {
+			continue
+		}
+
+		result.Text = verdict.Text
+		result.Action = max(result.Action, verdict.Action)
+		result.Reasons = append(result.Reasons, verdict.Reason)
+
+		if verdict.Action == Reject {
+			break
+		}
+	}
+	return result
+}
+
+// Reload reloads the terms of every filter that supports it.
+func (p *Pipeline) Reload(ctx context.Context) error {
+	var errs []error
+	for _, f := range p.filters {
+		if r, ok := f.(Reloader); ok {
+			errs = append(errs, r.Reload(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
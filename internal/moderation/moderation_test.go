@@ -0,0 +1,136 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestPipeline(t *testing.T) *Pipeline {
+	t.Helper()
+	words := StaticSource{"kerfuffle", "sharbert", "fornax"}
+	p := NewPipeline(
+		&WordFilter{Words: NewWordList(words), Action: Censor},
+		&RegexFilter{Patterns: NewPatternList(StaticSource{`buy\s+followers`}), Action: Flag},
+		NewLeetspeakFilter(words, Censor),
+		&URLFilter{Domains: NewWordList(StaticSource{"spam.example"}), Action: Reject},
+	)
+	if err := p.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	return p
+}
+
+func TestPipelineCheck(t *testing.T) {
+	p := newTestPipeline(t)
+
+	tests := []struct {
+		name       string
+		text       string
+		wantAction Action
+		wantText   string
+	}{
+		{
+			name:       "Clean text",
+			text:       "I had something interesting for breakfast",
+			wantAction: Allow,
+			wantText:   "I had something interesting for breakfast",
+		},
+		{
+			name:       "Word with punctuation",
+			text:       "What a Kerfuffle! Ugh, fornax,",
+			wantAction: Censor,
+			wantText:   "What a ****! Ugh, ****,",
+		},
+		{
+			name:       "Fullwidth and accented letters",
+			text:       "ｓｈａｒｂｅｒｔ or shárbert",
+			wantAction: Censor,
+			wantText:   "**** or ****",
+		},
+		{
+			name:       "Leetspeak",
+			text:       "such a f0rn4x",
+			wantAction: Censor,
+			wantText:   "such a ****",
+		},
+		{
+			name:       "Flagged pattern",
+			text:       "Buy  followers today",
+			wantAction: Flag,
+			wantText:   "Buy  followers today",
+		},
+		{
+			name:       "Flagged and censored",
+			text:       "buy followers, kerfuffle",
+			wantAction: Flag,
+			wantText:   "buy followers, ****",
+		},
+		{
+			name:       "Blocked subdomain URL",
+			text:       "see https://www.promo.spam.example/deal",
+			wantAction: Reject,
+			wantText:   "see https://www.promo.spam.example/deal",
+		},
+		{
+			name:       "Allowed URL",
+			text:       "see https://example.com/spam.example",
+			wantAction: Allow,
+			wantText:   "see https://example.com/spam.example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Check(tt.text)
+			if got.Action != tt.wantAction {
+				t.Errorf("Check() action = %v, want %v (reasons %v)", got.Action, tt.wantAction, got.Reasons)
+			}
+			if got.Text != tt.wantText {
+				t.Errorf("Check() text = %q, want %q", got.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestWordListReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# banned words\nkerfuffle\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &WordFilter{Words: NewWordList(FileSource(path)), Action: Censor}
+	if err := f.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := f.Check("kerfuffle sharbert"); got.Text != "**** sharbert" {
+		t.Errorf("Check() text = %q, want %q", got.Text, "**** sharbert")
+	}
+
+	if err := os.WriteFile(path, []byte("sharbert\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := f.Check("kerfuffle sharbert"); got.Text != "kerfuffle ****" {
+		t.Errorf("Check() after reload text = %q, want %q", got.Text, "kerfuffle ****")
+	}
+}
+
+func TestPipelineRejectStopsChain(t *testing.T) {
+	p := NewPipeline(
+		&URLFilter{Domains: NewWordList(StaticSource{"spam.example"}), Action: Reject},
+		&WordFilter{Words: NewWordList(StaticSource{"kerfuffle"}), Action: Censor},
+	)
+	if err := p.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	got := p.Check("kerfuffle spam.example")
+	if got.Action != Reject || !slices.Equal(got.Reasons, []string{"blocked URL"}) {
+		t.Errorf("Check() = %+v, want a single reject for the blocked URL", got)
+	}
+}
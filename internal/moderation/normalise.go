@@ -0,0 +1,122 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalised is text folded for matching, along with where each of its bytes came from in the original.
+// Matches found in the normalised text can then be mapped back, to censor the original.
+type normalised struct {
+	text   string
+	starts []int
+	ends   []int
+}
+
+// normalise folds text for matching: compatibility characters are decomposed (e.g. fullwidth
+// letters and ligatures), diacritics are dropped and everything is lowercased.
+// If fold is set, it is applied to each resulting rune as well.
+func normalise(text string, fold func(rune) rune) normalised {
+	var b strings.Builder
+	n := normalised{
+		starts: make([]int, 0, len(text)),
+		ends:   make([]int, 0, len(text)),
+	}
+
+	for offset, r := range text {
+		size := utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			size = 1
+		}
+
+		for _, d := range norm.NFKD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			d = unicode.ToLower(d)
+			if fold != nil {
+				d = fold(d)
+			}
+			before := b.Len()
+			b.WriteRune(d)
+			for range b.Len() - before {
+				n.starts = append(n.starts, offset)
+				n.ends = append(n.ends, offset+size)
+			}
+		}
+	}
+
+	n.text = b.String()
+	return n
+}
+
+// span maps a byte range in the normalised text back to a byte range in the original.
+func (n normalised) span(start, end int) (int, int) {
+	return n.starts[start], n.ends[end-1]
+}
+
+// words returns the byte ranges of each run of letters and digits in the normalised text.
+func (n normalised) words() [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range n.text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(n.text)})
+	}
+	return words
+}
+
+// foldLeetspeak maps common character substitutions back to the letters they stand in for.
+func foldLeetspeak(r rune) rune {
+	switch r {
+	case '4', '@':
+		return 'a'
+	case '8':
+		return 'b'
+	case '3':
+		return 'e'
+	case '6', '9':
+		return 'g'
+	case '1':
+		return 'i'
+	case '0':
+		return 'o'
+	case '5', '$':
+		return 's'
+	case '7', '+':
+		return 't'
+	default:
+		return r
+	}
+}
+
+// censorSpans replaces each byte range of the original text with the censor mask.
+// Spans must be in order and must not overlap.
+func censorSpans(text string, spans [][2]int) string {
+	const mask = "****"
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s[0] < last {
+			continue
+		}
+		b.WriteString(text[last:s[0]])
+		b.WriteString(mask)
+		last = s[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
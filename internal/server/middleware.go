@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/corygyarmathy/chirpy/internal/handlers"
+)
+
+// requireRole only lets a request through if it was authenticated by a user with at least the given role.
+// It must be wrapped in auth.Middleware.Required, which stores the claims it reads.
+// The role is read from the token. Changing a user's role revokes their access tokens, so it is never stale.
+func requireRole(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		if !ok {
+			handlers.RespondWithError(w, http.StatusUnauthorized, "no authenticated user in request context", nil)
+			return
+		}
+
+		if !claims.Role.Includes(role) {
+			handlers.RespondWithError(w, http.StatusForbidden, "user does not have the "+string(role)+" role", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireScope only lets a request through if its access token was issued with the given scope.
+// Like requireRole, it must be wrapped in auth.Middleware.Required.
+// Tokens for third-party clients are limited to the scopes the user granted them.
+func requireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		if !ok {
+			handlers.RespondWithError(w, http.StatusUnauthorized, "no authenticated user in request context", nil)
+			return
+		}
+
+		if !claims.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy", error="insufficient_scope", scope="`+scope+`"`)
+			handlers.RespondWithError(w, http.StatusForbidden, "token does not have the "+scope+" scope", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corygyarmathy/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestRequireRole(t *testing.T) {
+	keyring, err := auth.NewKeyring(auth.NewHMACKey(auth.DefaultKeyID, []byte("secret")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	userToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), Role: auth.RoleUser, ExpiresIn: time.Hour})
+	moderatorToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), Role: auth.RoleModerator, ExpiresIn: time.Hour})
+	adminToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), Role: auth.RoleAdmin, ExpiresIn: time.Hour})
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+	}{
+		{
+			name:       "No token",
+			authHeader: "",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Invalid token",
+			authHeader: "Bearer invalid.token.string",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "User",
+			authHeader: "Bearer " + userToken,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Moderator",
+			authHeader: "Bearer " + moderatorToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Admin",
+			authHeader: "Bearer " + adminToken,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	handler := auth.NewMiddleware(keyring, auth.NewMemoryDenylist(10)).Required(requireRole(auth.RoleModerator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/moderation", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("requireRole() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	keyring, err := auth.NewKeyring(auth.NewHMACKey(auth.DefaultKeyID, []byte("secret")))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	readToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), ExpiresIn: time.Hour, Scopes: []string{auth.ScopeChirpsRead}})
+	writeToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), ExpiresIn: time.Hour, Scopes: []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite}})
+	loginToken, _ := auth.MakeJWT(keyring, auth.TokenOptions{UserID: uuid.New(), ExpiresIn: time.Hour, Scopes: auth.AllScopes})
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+	}{
+		{
+			name:       "No token",
+			authHeader: "",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Read only token",
+			authHeader: "Bearer " + readToken,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Write token",
+			authHeader: "Bearer " + writeToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Login token",
+			authHeader: "Bearer " + loginToken,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	handler := auth.NewMiddleware(keyring, auth.NewMemoryDenylist(10)).Required(requireScope(auth.ScopeChirpsWrite, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("requireScope() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusForbidden && !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+				t.Errorf("requireScope() WWW-Authenticate = %q, want insufficient_scope", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
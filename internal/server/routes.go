@@ -4,13 +4,23 @@ package server
 import (
 	"net/http"
 
+	"github.com/corygyarmathy/chirpy/internal/auth"
 	"github.com/corygyarmathy/chirpy/internal/handlers"
 )
 
-func NewMux(api *handlers.API) *http.ServeMux {
+func NewMux(api *handlers.API, keyring *auth.Keyring, denylist auth.Denylist) *http.ServeMux {
 	const filepathRoot = "."
 
 	mux := http.NewServeMux()
+	authn := auth.NewMiddleware(keyring, denylist)
+
+	// Each route declares whether it needs an access token: required routes respond 401 without a valid one,
+	// and 403 if the token wasn't issued with the scope they need,
+	// optional routes treat the request as anonymous, and public routes don't look at it.
+	required := func(scope string, h http.HandlerFunc) http.Handler { return authn.Required(requireScope(scope, h)) }
+	optional := func(h http.HandlerFunc) http.Handler { return authn.Optional(h) }
+	moderator := func(h http.HandlerFunc) http.Handler { return authn.Required(requireRole(auth.RoleModerator, h)) }
+	admin := func(h http.HandlerFunc) http.Handler { return authn.Required(requireRole(auth.RoleAdmin, h)) }
 
 	mux.Handle("/app/",
 		api.MetricsMiddleware(
@@ -19,18 +29,66 @@ func NewMux(api *handlers.API) *http.ServeMux {
 	)
 
 	mux.HandleFunc("GET /api/healthz", handlers.Readiness)
-	mux.HandleFunc("GET /api/chirps", api.GetChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", api.GetChirpByID)
-	mux.HandleFunc("POST /api/chirps", api.CreateChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", api.DeleteChirp)
-	mux.HandleFunc("PUT /api/users", api.UpdateUser)
+	mux.HandleFunc("GET /.well-known/jwks.json", api.JWKS)
+	mux.Handle("GET /api/chirps", optional(api.GetChirps))
+	mux.Handle("GET /api/chirps/search", optional(api.SearchChirps))
+	mux.Handle("GET /api/chirps/{chirpID}", optional(api.GetChirpByID))
+	mux.Handle("POST /api/chirps", required(auth.ScopeChirpsWrite, api.CreateChirp))
+	mux.Handle("PUT /api/chirps/{chirpID}", required(auth.ScopeChirpsWrite, api.UpdateChirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}", required(auth.ScopeChirpsWrite, api.DeleteChirp))
+	mux.Handle("GET /api/chirps/{chirpID}/revisions", optional(api.GetChirpRevisions))
+	mux.Handle("GET /api/chirps/{chirpID}/thread", optional(api.GetChirpThread))
+	mux.Handle("POST /api/chirps/{chirpID}/like", required(auth.ScopeChirpsWrite, api.LikeChirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", required(auth.ScopeChirpsWrite, api.UnlikeChirp))
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", required(auth.ScopeChirpsWrite, api.Rechirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}/rechirp", required(auth.ScopeChirpsWrite, api.UndoRechirp))
+	mux.Handle("POST /api/chirps/{chirpID}/report", required(auth.ScopeChirpsWrite, api.ReportChirp))
+	mux.Handle("PUT /api/users", required(auth.ScopeAccountWrite, api.UpdateUser))
 	mux.HandleFunc("POST /api/users", api.CreateUser)
+	mux.HandleFunc("POST /api/users/verify", api.VerifyEmail)
+	mux.Handle("POST /api/users/2fa/enroll", required(auth.ScopeAccountWrite, api.EnrollTwoFactor))
+	mux.Handle("POST /api/users/2fa/verify", required(auth.ScopeAccountWrite, api.VerifyTwoFactor))
+	mux.Handle("GET /api/passkeys", required(auth.ScopeAccountWrite, api.GetPasskeys))
+	mux.Handle("POST /api/passkeys/register/begin", required(auth.ScopeAccountWrite, api.BeginPasskeyRegistration))
+	mux.Handle("POST /api/passkeys/register/finish", required(auth.ScopeAccountWrite, api.FinishPasskeyRegistration))
+	mux.Handle("DELETE /api/passkeys/{passkeyID}", required(auth.ScopeAccountWrite, api.DeletePasskey))
+	mux.Handle("POST /api/users/{userID}/follow", required(auth.ScopeFollowsWrite, api.FollowUser))
+	mux.Handle("DELETE /api/users/{userID}/follow", required(auth.ScopeFollowsWrite, api.UnfollowUser))
+	mux.HandleFunc("GET /api/users/{userID}/followers", api.GetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", api.GetFollowing)
+	mux.Handle("GET /api/users/{userID}/mentions", optional(api.GetMentions))
+	mux.Handle("GET /api/timeline", required(auth.ScopeChirpsRead, api.GetTimeline))
+	mux.HandleFunc("GET /api/hashtags/trending", api.GetTrendingHashtags)
+	mux.Handle("GET /api/hashtags/{tag}/chirps", optional(api.GetChirpsByHashtag))
 	mux.HandleFunc("POST /api/login", api.LoginUser)
+	mux.HandleFunc("POST /api/login/2fa", api.LoginTwoFactor)
+	mux.HandleFunc("POST /api/login/passkey/begin", api.BeginPasskeyLogin)
+	mux.HandleFunc("POST /api/login/passkey/finish", api.FinishPasskeyLogin)
+	mux.HandleFunc("POST /api/login/oidc/begin", api.BeginOIDCLogin)
+	mux.HandleFunc("POST /api/login/oidc/finish", api.FinishOIDCLogin)
 	mux.HandleFunc("POST /api/refresh", api.RefreshLogin)
 	mux.HandleFunc("POST /api/revoke", api.RevokeLogin)
+	mux.HandleFunc("POST /api/password/forgot", api.ForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", api.ResetPassword)
+	mux.Handle("GET /api/oauth/clients", required(auth.ScopeAccountWrite, api.GetOAuthClients))
+	mux.Handle("POST /api/oauth/clients", required(auth.ScopeAccountWrite, api.RegisterOAuthClient))
+	mux.Handle("GET /api/oauth/authorize", required(auth.ScopeAccountWrite, api.GetOAuthConsent))
+	mux.Handle("POST /api/oauth/authorize", required(auth.ScopeAccountWrite, api.AuthorizeOAuthClient))
+	mux.HandleFunc("POST /api/oauth/token", api.ExchangeOAuthToken)
+	mux.HandleFunc("POST /api/oauth/introspect", api.IntrospectOAuthToken)
+	mux.Handle("GET /api/oauth/grants", required(auth.ScopeAccountWrite, api.GetOAuthGrants))
+	mux.Handle("DELETE /api/oauth/grants/{clientID}", required(auth.ScopeAccountWrite, api.RevokeOAuthGrant))
+	mux.Handle("GET /api/sessions", required(auth.ScopeAccountWrite, api.GetSessions))
+	mux.Handle("DELETE /api/sessions", required(auth.ScopeAccountWrite, api.RevokeAllSessions))
+	mux.Handle("DELETE /api/sessions/{sessionID}", required(auth.ScopeAccountWrite, api.RevokeSession))
 
-	mux.HandleFunc("GET /admin/metrics", api.Metrics)
-	mux.HandleFunc("POST /admin/reset", api.Reset)
+	mux.Handle("GET /admin/metrics", admin(api.Metrics))
+	mux.Handle("POST /admin/reset", admin(api.Reset))
+	mux.Handle("GET /admin/moderation", moderator(api.GetModerationQueue))
+	mux.Handle("POST /admin/moderation/{itemID}/{action}", moderator(api.DecideModerationItem))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(api.SetUserRole))
+	mux.Handle("DELETE /admin/users/{userID}/role", admin(api.RevokeUserRole))
+	mux.Handle("GET /admin/users/{userID}/role-changes", admin(api.GetRoleChanges))
 
 	mux.HandleFunc("POST /api/polka/webhooks", api.Webhooks)
 
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/corygyarmathy/chirpy/internal/auth"
 	"github.com/corygyarmathy/chirpy/internal/database"
+	"github.com/corygyarmathy/chirpy/internal/email"
 	"github.com/corygyarmathy/chirpy/internal/handlers"
+	"github.com/corygyarmathy/chirpy/internal/moderation"
 	"github.com/corygyarmathy/chirpy/internal/server"
+	"github.com/go-webauthn/webauthn/webauthn"
 	_ "github.com/lib/pq"
 )
 
@@ -24,9 +35,15 @@ func main() {
 	if platform == "" {
 		log.Fatal("PLATFORM environment variable must be set")
 	}
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable must be set")
+	// JWT_SECRET is still accepted alone, or alongside JWT_KEYS_FILE to verify tokens it signed
+	keyring, err := auth.LoadKeyring(os.Getenv("JWT_KEYS_FILE"), os.Getenv("JWT_SECRET"))
+	if err != nil {
+		log.Fatalf("JWT keyring load error: %v\n", err)
+	}
+	// TOTP secrets have to be read back to check codes, so they are encrypted rather than hashed
+	totpCipher, err := auth.ParseSecretCipher(os.Getenv("TOTP_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("TOTP_ENCRYPTION_KEY must be set to a base64 encoded 32 byte key: %v\n", err)
 	}
 	polkaKey := os.Getenv("POLKA_KEY")
 	if polkaKey == "" {
@@ -43,10 +60,44 @@ func main() {
 		}
 	}()
 
-	dbQueries := database.New(db)
-	api := handlers.New(dbQueries, platform, jwtSecret, polkaKey)
+	moderator := newModerationPipeline(database.New(db))
+	if err := moderator.Reload(context.Background()); err != nil {
+		log.Fatalf("Moderation terms load error: %v\n", err)
+	}
+	go reloadOnHangup(moderator)
 
-	mux := server.NewMux(api)
+	denylist, err := newDenylist(database.New(db))
+	if err != nil {
+		log.Fatalf("Access token denylist error: %v\n", err)
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("Mailer error: %v\n", err)
+	}
+	// Posting chirps requires a verified email address unless REQUIRE_EMAIL_VERIFICATION is false
+	requireVerifiedEmail := os.Getenv("REQUIRE_EMAIL_VERIFICATION") != "false"
+
+	passkeys, err := newPasskeys()
+	if err != nil {
+		log.Fatalf("Passkey config error: %v\n", err)
+	}
+
+	oidcProvider, err := newOIDCProvider()
+	if err != nil {
+		log.Fatalf("OIDC provider error: %v\n", err)
+	}
+
+	api := handlers.New(db, platform, keyring, denylist, polkaKey, moderator, mailer, requireVerifiedEmail, totpCipher, passkeys, oidcProvider)
+
+	// Roles can only be granted by an admin, so the first one is named in the environment
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		if err := api.BootstrapAdmin(context.Background(), adminEmail); err != nil {
+			log.Printf("Bootstrap admin error: %v\n", err)
+		}
+	}
+
+	mux := server.NewMux(api, keyring, denylist)
 
 	srv := &http.Server{
 		Addr:           ":" + port,
@@ -59,3 +110,118 @@ func main() {
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
 	log.Fatal(srv.ListenAndServe())
 }
+
+// newDenylist returns the store of revoked access tokens named by ACCESS_TOKEN_DENYLIST.
+// The Postgres denylist is shared by every instance, while the in-memory one only suits a single instance.
+func newDenylist(db *database.Queries) (auth.Denylist, error) {
+	switch store := os.Getenv("ACCESS_TOKEN_DENYLIST"); store {
+	case "", "postgres":
+		return auth.NewPostgresDenylist(db), nil
+	case "memory":
+		capacity := 100_000
+		if value := os.Getenv("ACCESS_TOKEN_DENYLIST_SIZE"); value != "" {
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("invalid ACCESS_TOKEN_DENYLIST_SIZE %q", value)
+			}
+			capacity = size
+		}
+		return auth.NewMemoryDenylist(capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown ACCESS_TOKEN_DENYLIST %q", store)
+	}
+}
+
+// newMailer returns the mailer named by MAILER: "smtp" sends through SMTP_ADDR,
+// and "log", the default, writes emails to MAIL_LOG_FILE, or stdout if it isn't set.
+func newMailer() (email.Mailer, error) {
+	switch mailer := os.Getenv("MAILER"); mailer {
+	case "", "log":
+		path := os.Getenv("MAIL_LOG_FILE")
+		if path == "" {
+			return email.NewLogMailer(os.Stdout), nil
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("opening mail log file: %v", err)
+		}
+		return email.NewLogMailer(f), nil
+	case "smtp":
+		addr, from := os.Getenv("SMTP_ADDR"), os.Getenv("SMTP_FROM")
+		if addr == "" || from == "" {
+			return nil, errors.New("SMTP_ADDR and SMTP_FROM must be set to send email through SMTP")
+		}
+		return email.NewSMTPMailer(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", mailer)
+	}
+}
+
+// newPasskeys configures WebAuthn for the relying party ID in WEBAUTHN_RP_ID, the site's domain,
+// accepting passkey ceremonies only from the comma-separated WEBAUTHN_RP_ORIGINS.
+// Both default to this server running locally.
+func newPasskeys() (*webauthn.WebAuthn, error) {
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = "localhost"
+	}
+	origins := []string{"http://localhost:8080"}
+	if value := os.Getenv("WEBAUTHN_RP_ORIGINS"); value != "" {
+		origins = strings.Split(value, ",")
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: "Chirpy",
+		RPOrigins:     origins,
+	})
+}
+
+// newOIDCProvider returns the OpenID Connect provider users can log in with, discovered from OIDC_ISSUER_URL,
+// or nil if it isn't set. OIDC_REDIRECT_URL is the page the provider sends users back to, which finishes the login.
+func newOIDCProvider() (*auth.OIDCProvider, error) {
+	issuerURL := os.Getenv("OIDC_ISSUER_URL")
+	if issuerURL == "" {
+		return nil, nil
+	}
+	clientID, redirectURL := os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_REDIRECT_URL")
+	if clientID == "" || redirectURL == "" {
+		return nil, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set to log in with OIDC_ISSUER_URL")
+	}
+	return auth.NewOIDCProvider(context.Background(), issuerURL, clientID, os.Getenv("OIDC_CLIENT_SECRET"), redirectURL)
+}
+
+// newModerationPipeline builds the chain of chirp filters. Each list of terms is read from
+// the file named by its environment variable if set, or from the moderation_terms table otherwise.
+func newModerationPipeline(db *database.Queries) *moderation.Pipeline {
+	termSource := func(envVar string, list string) moderation.Source {
+		if path := os.Getenv(envVar); path != "" {
+			return moderation.FileSource(path)
+		}
+		return moderation.SourceFunc(func(ctx context.Context) ([]string, error) {
+			return db.GetModerationTerms(ctx, list)
+		})
+	}
+
+	words := termSource("MODERATION_WORDS_FILE", "word")
+
+	return moderation.NewPipeline(
+		&moderation.WordFilter{Words: moderation.NewWordList(words), Action: moderation.Censor},
+		&moderation.RegexFilter{Patterns: moderation.NewPatternList(termSource("MODERATION_PATTERNS_FILE", "pattern")), Action: moderation.Flag},
+		moderation.NewLeetspeakFilter(words, moderation.Censor),
+		&moderation.URLFilter{Domains: moderation.NewWordList(termSource("MODERATION_DOMAINS_FILE", "domain")), Action: moderation.Reject},
+	)
+}
+
+// reloadOnHangup reloads the moderation terms whenever the process receives SIGHUP.
+func reloadOnHangup(moderator *moderation.Pipeline) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := moderator.Reload(context.Background()); err != nil {
+			log.Printf("Moderation terms reload error: %v\n", err)
+			continue
+		}
+		log.Println("Moderation terms reloaded")
+	}
+}